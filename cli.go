@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"example.com/artificial-life/game"
+	"example.com/artificial-life/neat"
+)
+
+var (
+	configPath      string
+	configOverrides []string
+	printConfig     bool
+	seed            uint64
+	tickLimit       int
+	outDir          string
+	headless        bool
+	fast            bool
+	realtime        bool
+	autosaveDir     string
+	autosaveTicks   int
+	autosaveMinutes float64
+	autosaveKeep    int
+	eventsPath      string
+	newickPath      string
+	hallOfFamePath  string
+	statsPath       string
+	statsInterval   int
+	metricsAddr     string
+	apiAddr         string
+	viewerAddr      string
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(name string, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "", "simulate a new world in a window", runNew},
+		{"headless", "", "simulate a new world without a window", runNew},
+		{"resume", "<checkpoint>", "continue a world from a checkpoint, a snapshot or the newest checkpoint in a directory", runResume},
+		{"replay", "<event-log>", "replay a recorded run from its checkpoints, checking it against its event log", runReplay},
+		{"inspect", "<genome-file>", "describe a brain, read from a genome, a checkpoint, a snapshot or a hall of fame", runInspect},
+		{"bench", "", "measure how fast the world is simulated", runBench},
+		{"train", "", "evolve brains in a small arena, generation by generation, to seed new worlds with", runTrain},
+		{"sweep", "<sweep-file>", "run every combination of a grid of parameters in parallel and summarize the outcomes", runSweep},
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %-15s %s\n", c.name, c.args, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun a command with -h to see its flags. Without a command, run is assumed.\n")
+}
+
+func runCommand(args []string) error {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage()
+		return nil
+	}
+
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return commands[i].run(name, args)
+}
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n", filepath.Base(os.Args[0]), name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func configFlags(fs *flag.FlagSet) {
+	fs.StringVar(&configPath, "config", "", "JSON file with the parameters of the world, missing ones take their default value")
+	fs.Func("set", "override a parameter of the world, e.g. -set animals.speed=80 (can be repeated)", func(s string) error {
+		configOverrides = append(configOverrides, s)
+		return nil
+	})
+	fs.Uint64Var(&seed, "seed", 0, "seed of the world, a random one is picked and printed when 0")
+	fs.StringVar(&brainPath, "brain", "", "start from mutated copies of this brain, read from a genome, a checkpoint, a snapshot or a hall of fame")
+}
+
+// A resumed world keeps its parameters and random state unless given new
+// ones.
+func resumeConfigFlags(fs *flag.FlagSet) {
+	fs.StringVar(&configPath, "config", "", "JSON file with new parameters for the world, missing ones take their default value")
+	fs.Func("set", "override a parameter of the world, e.g. -set animals.speed=80 (can be repeated)", func(s string) error {
+		configOverrides = append(configOverrides, s)
+		return nil
+	})
+	fs.Uint64Var(&seed, "seed", 0, "reseed the world, 0 keeping its random state")
+}
+
+func runFlags(fs *flag.FlagSet) {
+	fs.IntVar(&tickLimit, "ticks", 0, "stop after simulating this many ticks (0 never stops)")
+	fs.BoolVar(&fast, "fast", false, "simulate as fast as possible instead of at the world's ticks per second, as headless runs always do")
+	fs.BoolVar(&realtime, "realtime", false, "simulate headless runs at the world's ticks per second instead of as fast as possible")
+	fs.StringVar(&outDir, "out", "", "write checkpoints, events, statistics and the phylogenetic tree to this directory")
+	fs.StringVar(&autosaveDir, "autosave-dir", "checkpoints", "directory where checkpoints are written")
+	fs.IntVar(&autosaveTicks, "autosave-ticks", 0, "write a checkpoint every N ticks (0 disables it)")
+	fs.Float64Var(&autosaveMinutes, "autosave-minutes", 0, "write a checkpoint every M minutes (0 disables it)")
+	fs.IntVar(&autosaveKeep, "autosave-keep", 5, "number of checkpoints to keep")
+	fs.StringVar(&eventsPath, "events", "", "append the events of the run to this JSON Lines file")
+	fs.StringVar(&newickPath, "newick", "", "write the phylogenetic tree of the run to this Newick file on exit")
+	fs.StringVar(&hallOfFamePath, "hall-of-fame", "", "write the fittest animals ever seen, brains included, to this file on exit")
+	fs.StringVar(&statsPath, "stats", "", "write population statistics to this file, CSV if it ends in .csv and JSON Lines otherwise")
+	fs.IntVar(&statsInterval, "stats-interval", 60, "ticks between two statistics samples")
+	fs.BoolVar(&printConfig, "print-config", false, "print the parameters of the world and exit")
+	serverFlags(fs)
+}
+
+func serverFlags(fs *flag.FlagSet) {
+	fs.StringVar(&metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address, e.g. localhost:9100")
+	fs.StringVar(&apiAddr, "api-addr", "", "serve the HTTP control API on this address, e.g. localhost:8080")
+	fs.StringVar(&viewerAddr, "viewer-addr", "", "serve a browser viewer and its websocket stream on this address, e.g. localhost:8080")
+}
+
+func parse(fs *flag.FlagSet, args []string, positional int) error {
+	fs.Parse(args)
+	if fs.NArg() != positional {
+		fs.Usage()
+		return fmt.Errorf("%s: expected %d arguments, got %d", fs.Name(), positional, fs.NArg())
+	}
+	return nil
+}
+
+// Outputs which weren't given explicitly go to the output directory, along
+// with the configuration of the world.
+func useOutDir(fs *flag.FlagSet) error {
+	if outDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	defaults := []struct {
+		flag string
+		path *string
+		name string
+	}{
+		{"autosave-dir", &autosaveDir, "checkpoints"},
+		{"events", &eventsPath, "events.jsonl"},
+		{"stats", &statsPath, "stats.csv"},
+		{"newick", &newickPath, "lineage.nwk"},
+		{"hall-of-fame", &hallOfFamePath, "hall-of-fame.json"},
+	}
+	for _, d := range defaults {
+		if !set[d.flag] {
+			*d.path = filepath.Join(outDir, d.name)
+		}
+	}
+
+	file, err := os.Create(filepath.Join(outDir, "config.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return game.Game.Write(file)
+}
+
+func simulate(fs *flag.FlagSet) error {
+	if printConfig {
+		return game.Game.Write(os.Stdout)
+	}
+	if err := useOutDir(fs); err != nil {
+		return err
+	}
+	startTick = game.Ticks
+	peakPopulation = len(game.Animals)
+	if tickLimit > 0 {
+		stopTick = game.Ticks + tickLimit
+	}
+
+	fmt.Println("Bienvenido")
+	if err := initOutputs(); err != nil {
+		return err
+	}
+	initController()
+	if fast || headless && !realtime {
+		controller.SetTicksPerSecond(0)
+	}
+	startServers()
+	var err error
+	if headless {
+		runHeadless()
+	} else {
+		err = runWindow()
+	}
+	closeOutputs()
+	return err
+}
+
+func runNew(name string, args []string) error {
+	fs := newFlagSet(name, "")
+	configFlags(fs)
+	runFlags(fs)
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	headless = name == "headless"
+	if printConfig {
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		return config.Write(os.Stdout)
+	}
+	if err := initWorld(""); err != nil {
+		return err
+	}
+	return simulate(fs)
+}
+
+func runResume(name string, args []string) error {
+	fs := newFlagSet(name, "<checkpoint>")
+	resumeConfigFlags(fs)
+	runFlags(fs)
+	fs.BoolVar(&headless, "headless", false, "run without a window")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	if err := initWorld(fs.Arg(0)); err != nil {
+		return err
+	}
+	if err := reconfigure(); err != nil {
+		return err
+	}
+	return simulate(fs)
+}
+
+func runReplay(name string, args []string) error {
+	fs := newFlagSet(name, "<event-log>")
+	fs.StringVar(&autosaveDir, "checkpoints", "checkpoints", "directory with the checkpoints of the run")
+	fs.BoolVar(&headless, "headless", false, "check the whole run against the log without opening a window")
+	serverFlags(fs)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	var err error
+	replay, err = game.NewReplay(autosaveDir, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Replaying ticks", replay.FirstTick(), "to", replay.LastTick())
+
+	if headless {
+		for game.Ticks < replay.LastTick() {
+			if err := replay.Step(); err != nil {
+				return err
+			}
+		}
+		fmt.Println("The run matches its event log")
+		return nil
+	}
+
+	initController()
+	startServers()
+	return runWindow()
+}
+
+// Reads a genome, or takes the brain of an animal from a checkpoint, a
+// snapshot or a hall of fame: the one with the given id or else the fittest
+// one.
+func readGenome(path string, animalId int) (*neat.Genome, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Animals json.RawMessage `json:"animals"`
+		Entries json.RawMessage `json:"entries"`
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte(game.CHECKPOINT_HEADER)):
+		err = game.LoadCheckpoint(path)
+	case json.Unmarshal(data, &probe) == nil && probe.Animals != nil:
+		err = game.LoadWorldFile(path)
+	case probe.Entries != nil:
+		return readChampion(path, animalId)
+	default:
+		var genome neat.Genome
+		if err := json.Unmarshal(data, &genome); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &genome, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var animal *game.Animal
+	if animalId != 0 {
+		animal = game.FindAnimal(animalId)
+		if animal == nil {
+			return nil, fmt.Errorf("%s: no living animal with id %d", path, animalId)
+		}
+	} else {
+		for _, a := range game.Animals {
+			if animal == nil || a.Info(false).Fitness > animal.Info(false).Fitness {
+				animal = a
+			}
+		}
+		if animal == nil {
+			return nil, fmt.Errorf("%s: there are no living animals", path)
+		}
+	}
+
+	info := animal.Info(true)
+	fmt.Printf("Animal %d (%s), generation %d, species %d, fitness %.1f, hp %d, age %d ticks\n\n",
+		info.Id, info.Type, info.Generation, info.Species, info.Fitness, info.Hp, info.Age)
+	return info.Brain, nil
+}
+
+func readChampion(path string, animalId int) (*neat.Genome, error) {
+	h, err := game.ReadHallOfFameFile(path)
+	if err != nil {
+		return nil, err
+	}
+	i := 0
+	if animalId != 0 {
+		i = slices.IndexFunc(h.Entries, func(e game.HallOfFameEntry) bool { return e.AnimalId == animalId })
+	}
+	if i < 0 || i >= len(h.Entries) {
+		return nil, fmt.Errorf("%s: no champion with id %d", path, animalId)
+	}
+	e := h.Entries[i]
+	fmt.Printf("Champion %d (%s), generation %d, species %d, fitness %.1f at tick %d\n\n",
+		e.AnimalId, e.AnimalType, e.Generation, e.Species, e.Fitness, e.Tick)
+	return e.Brain, nil
+}
+
+func runInspect(name string, args []string) error {
+	fs := newFlagSet(name, "<genome-file>")
+	animalId := fs.Int("animal", 0, "animal whose brain is inspected, the fittest one when 0")
+	var drawings []string
+	fs.Func("draw", "draw the brain to this .dot, .svg or .png file (can be repeated)", func(s string) error {
+		drawings = append(drawings, s)
+		return nil
+	})
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	genome, err := readGenome(fs.Arg(0), *animalId)
+	if err != nil {
+		return err
+	}
+	for _, path := range drawings {
+		if err := drawGenome(genome, path); err != nil {
+			return err
+		}
+		fmt.Println("Drew the brain to", path)
+	}
+	return genome.WriteSummary(os.Stdout)
+}
+
+func drawGenome(genome *neat.Genome, path string) error {
+	var write func(io.Writer) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		write = genome.WriteDOT
+	case ".svg":
+		write = genome.WriteSVG
+	case ".png":
+		write = genome.WritePNG
+	default:
+		return fmt.Errorf("%s: can only draw to .dot, .svg or .png files", path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func runBench(name string, args []string) error {
+	fs := newFlagSet(name, "")
+	configFlags(fs)
+	fs.IntVar(&tickLimit, "ticks", 1000, "ticks to simulate")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if tickLimit <= 0 {
+		return errors.New("bench: -ticks must be positive")
+	}
+	if seed == 0 {
+		seed = 1
+	}
+
+	if err := initWorld(""); err != nil {
+		return err
+	}
+
+	durations := make([]time.Duration, tickLimit)
+	start := time.Now()
+	for i := range durations {
+		tickStart := time.Now()
+		game.Tick()
+		durations[i] = time.Since(tickStart)
+	}
+	elapsed := time.Since(start)
+
+	slices.Sort(durations)
+	percentile := func(p float64) time.Duration {
+		return durations[int(p*float64(len(durations)-1))]
+	}
+	fmt.Printf("%d ticks in %v, %.1f ticks/s\n", tickLimit, elapsed.Round(time.Millisecond), float64(tickLimit)/elapsed.Seconds())
+	fmt.Printf("tick duration: mean %v, p50 %v, p99 %v, max %v\n",
+		elapsed/time.Duration(tickLimit), percentile(0.5), percentile(0.99), durations[len(durations)-1])
+	fmt.Printf("%d animals, %d species and %d plants at the end\n", len(game.Animals), len(game.SpeciesList), len(game.FoodBlocks))
+	return nil
+}
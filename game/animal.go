@@ -2,7 +2,7 @@ package game
 
 import (
 	"math"
-	"math/rand"
+	"slices"
 	"sync"
 
 	_ "image/png"
@@ -26,17 +26,20 @@ const (
 	HUNTER
 )
 
-const (
-	HUNGER_PERIOD = float64(1.0)
-)
-
 type Animal struct {
+	id              int
+	parentId        int
+	generation      int
+	birthTick       int
+	species         int
 	x               float64
 	y               float64
 	w               float64
 	h               float64
 	dirTheta        float64
 	speed           float64
+	lastDx          float64
+	lastDy          float64
 	turningState    TurningState
 	turningRate     float64
 	ticksUntilHurt  int
@@ -45,17 +48,43 @@ type Animal struct {
 	viewingDistance int
 	hp              int
 	animalType      AnimalType
+	diet            Diet
+	biteRate        float64
+	hpDigested      float64
+	eating          bool
+	meal            FoodType
+	killedBy        int
+	// Meat its killer ate on the spot. An animal is pruned in the tick it
+	// is killed, so snapshots never see it set.
+	meatEaten       int
 	// mu              sync.Mutex
 	ticksToAppear   int
-	fitnessGoal     int
-	fitness         int
+	fitnessGoal     float64
+	fitness         float64
+	lifetime        int
+	foodEaten       float64
+	offspring       int
+	distance        float64
+	kills           int
 	reproCoolDown   int
 	brain           *neat.Genome
 	sprite          *pixel.Sprite
+	rays            []Ray
 }
 
-func InitAnimal(x, y float64, animalType AnimalType) *Animal {
+// What one of the rays an animal sees through met the last time it looked.
+type Ray struct {
+	Theta float64
+	// Steps, of half the animal's width each, at which the ray met a plant
+	// and meat, 0 if it met none.
+	Plant int
+	Meat  int
+	// Steps the ray went before terrain blocked it or it ran out of sight.
+	Length  int
+	Blocked bool
+}
 
+func loadAnimalSprite(animalType AnimalType) *pixel.Sprite {
 	spriteFile := ""
 	if animalType == PREY {
 		spriteFile = "./img/looking-left.png"
@@ -68,32 +97,48 @@ func InitAnimal(x, y float64, animalType AnimalType) *Animal {
 		panic(err)
 	}
 
-	sprite := pixel.NewSprite(pic, pic.Bounds())
+	return pixel.NewSprite(pic, pic.Bounds())
+}
+
+func InitAnimal(x, y float64, animalType AnimalType) *Animal {
+	brain := neat.CreateGenome(0, 2*Game.Animals.FovRays+1, 3)
+	brain.InitializeFromInitialConfig()
+	return initAnimal(x, y, animalType, brain)
+}
+
+func initAnimal(x, y float64, animalType AnimalType, brain *neat.Genome) *Animal {
+	sprite := loadAnimalSprite(animalType)
 
 	w := sprite.Frame().Max.X - sprite.Frame().Min.X
 	h := sprite.Frame().Max.Y - sprite.Frame().Min.Y
-	fov := math.Pi / 2
-	fovRays := 6
-
-	brain := neat.CreateGenome(0, fovRays+1, 3)
-	brain.InitializeFromInitialConfig()
+	config := Game.Animals
+	fov := config.FovDegrees * math.Pi / 180
+	fovRays := config.FovRays
 
-	return &Animal{x: x,
+	a := &Animal{id: newAnimalId(),
+		birthTick:       Ticks,
+		x:               x,
 		y:               y,
 		w:               w,
 		h:               h,
-		speed:           60,
-		turningRate:     0.125,
-		ticksUntilHurt:  20,
-		hp:              20,
+		speed:           config.Speed,
+		turningRate:     config.TurningRate,
+		ticksUntilHurt:  config.HungerPeriod,
+		hp:              config.Hp,
 		fov:             fov,
 		fovRays:         fovRays,
-		viewingDistance: 50,
+		viewingDistance: config.ViewingDistance,
 		animalType:      animalType,
-		fitnessGoal:     Game.TicksPerSecond * 30,
+		diet:            InitDiet(animalType),
+		biteRate:        config.BiteRate,
+		fitnessGoal:     Game.Fitness.InitialGoal,
 		brain:           brain,
 		sprite:          sprite,
 	}
+	Lineage.recordBirth(a)
+	a.assignSpecies(0)
+
+	return a
 }
 
 func (a *Animal) IncrementPos(dx, dy float64) {
@@ -115,6 +160,11 @@ func (a *Animal) GetTheta() float64 {
 	return a.dirTheta
 }
 
+// How many steps of half its width the animal sees.
+func (a *Animal) GetViewingDistance() int {
+	return a.viewingDistance
+}
+
 func (a *Animal) GetSpeed() float64 {
 	return a.speed
 }
@@ -131,7 +181,10 @@ func (a *Animal) SetTurningState(x TurningState) {
 	a.turningState = x
 }
 
-func (a *Animal) updateDirection() {
+// Sensing and thinking only read the world, so every animal can do it
+// concurrently before any of them acts.
+func (a *Animal) Think(wg *sync.WaitGroup) {
+	defer wg.Done()
 	a.see()
 	a.brain.SetHpInput(a.hp)
 	a.brain.Think()
@@ -153,7 +206,9 @@ func (a *Animal) updateDirection() {
 	if noTurn > turnLeft && noTurn > turnRight {
 		a.turningState = STRAIGHT
 	}
+}
 
+func (a *Animal) updateDirection() {
 	switch a.turningState {
 	case LEFT:
 		a.TurnDelta(a.turningRate)
@@ -162,163 +217,183 @@ func (a *Animal) updateDirection() {
 	}
 }
 
-func (a *Animal) Update(wg *sync.WaitGroup) {
-	defer wg.Done()
-	a.ticksUntilHurt--
+func (a *Animal) Update() {
+	a.ticksUntilHurt -= 1 + a.crowdingHunger()
 	if a.ticksUntilHurt <= 0 {
 		a.hp--
-		a.ticksUntilHurt = 20
+		a.ticksUntilHurt = Game.Animals.HungerPeriod
 	}
 
-	a.fitness++
-
-	if a.ticksUntilHurt > 20 * 10 && a.reproCoolDown <= 0 {
-		newAnimal := a.Copy()
-		newAnimal.TurnDelta(rand.NormFloat64() * math.Pi)
-		newAnimal.hp = 20
-		newAnimal.ticksUntilHurt = 100
-		newAnimal.ticksToAppear = Game.TicksPerSecond + 1
-		newAnimal.fitness = 0
-		newAnimal.fitnessGoal = a.fitness
-		newAnimal.brain.Mutate()
-
-		newAnimals = append(newAnimals, newAnimal)
-
-
-		if a.fitness > 20*40 {
-			newAnimal := a.Copy()
-			newAnimal.TurnDelta(rand.NormFloat64() * math.Pi)
-			newAnimal.hp = 20
-			newAnimal.ticksUntilHurt = 100
-			newAnimal.ticksToAppear = Game.TicksPerSecond + 1
-			newAnimal.fitness = 0
-			newAnimal.fitnessGoal = a.fitness
-			newAnimal.brain.Mutate()
-
-			newAnimals = append(newAnimals, newAnimal)
+	a.lifetime++
+	a.fitness = Fitness(a)
+
+	if a.ticksUntilHurt > Game.Animals.ReproductionThreshold && a.reproCoolDown <= 0 && a.reachedFitnessGoal() {
+		if atMaximum(a.animalType) {
+			emit(Event{Type: POPULATION, Cause: CAUSE_AT_MAXIMUM, Animal: a.id, AnimalType: a.animalType.String(),
+				Count: CountAnimals(a.animalType), X: a.x, Y: a.y})
+		} else {
+			children := []int{a.spawnChild(false).id}
+			if a.fitness > Game.Animals.TwinsFitness && !atMaximum(a.animalType) {
+				children = append(children, a.spawnChild(false).id)
+			}
+			emit(Event{Type: MATE, Animal: a.id, Children: children, X: a.x, Y: a.y})
 		}
-		a.reproCoolDown = 20*10
+		a.reproCoolDown = Game.Animals.ReproductionCooldown
 	} else {
 		a.reproCoolDown--
 	}
 
 
-	if a.animalType == PREY {
-		a.CheckNHandlePlantCollisions()
+	wasEating := a.eating
+	a.eating = false
+	a.CheckNHandlePlantCollisions()
+	a.CheckNHandlePreyCollisions()
+	a.CheckNHandleCorpseCollisions()
+	if a.eating && !wasEating {
+		emit(Event{Type: EAT, Animal: a.id, Food: a.meal.String(), X: a.x, Y: a.y})
 	}
 
 	a.updateDirection()
-	dx := math.Cos(a.dirTheta) * a.speed * float64(1.0/20.0)
-	dy := math.Sin(a.dirTheta) * a.speed * float64(1.0/20.0)
-	a.x = a.x + dx
-	a.y = a.y + dy
+	a.move()
+}
+
+func (a *Animal) move() {
+	cx, cy := a.x+a.w/2, a.y+a.h/2
+	cost := TerrainMap.MovementCost(cx, cy)
+	dx := math.Cos(a.dirTheta) * a.speed * float64(1.0/20.0) / cost
+	dy := math.Sin(a.dirTheta) * a.speed * float64(1.0/20.0) / cost
+	if Game.Topology == SOFT {
+		px, py := borderPush(cx, cy)
+		dx += px
+		dy += py
+	}
+
+	// Slide along obstacles instead of getting stuck on them.
+	switch {
+	case TerrainMap.IsPassable(cx+dx, cy+dy):
+	case TerrainMap.IsPassable(cx+dx, cy):
+		dy = 0
+	case TerrainMap.IsPassable(cx, cy+dy):
+		dx = 0
+	default:
+		dx, dy = 0, 0
+	}
+
+	a.x, a.y = WrapPos(a.x+dx, a.y+dy)
+	a.distance += math.Hypot(dx, dy)
+	a.lastDx = dx
+	a.lastDy = dy
+}
+
+func (a *Animal) GetLastMove() (dx, dy float64) {
+	return a.lastDx, a.lastDy
 }
 
 func (a *Animal) see() {
 	fov := a.fov
 	rays := float64(a.fovRays)
+	a.rays = a.rays[:0]
 
-	i := 0
-	for angle := a.dirTheta - fov/2; angle <= a.dirTheta+fov/2; angle += fov / rays {
+	for i := 0; i < a.fovRays; i++ {
+		angle := a.dirTheta - fov/2 + fov*float64(i)/(rays-1)
 		a.castRay(i, angle)
-		i++
 	}
 }
 
 func (a *Animal) castRay(idx int, theta float64) {
 	vec := pixel.ZV
-	vec.X = a.x + a.w/2
-	vec.Y = a.y + a.h/2
+	vec.X, vec.Y = a.eyes()
 
 	dir := pixel.ZV
 	dir.X = math.Cos(theta) * a.w / 2
 	dir.Y = math.Sin(theta) * a.w / 2
 
-	for i := 1; i <= a.viewingDistance; i++ {
+	seenPlant := false
+	seenMeat := false
+	ray := Ray{Theta: theta}
+	for i := 1; i <= a.viewingDistance && !(seenPlant && seenMeat); i++ {
 		vec = vec.Add(dir)
-		x := vec.X - math.Mod(vec.X, 16.0)
-		y := vec.Y - math.Mod(vec.Y, 16.0)
-		// pos := pixel.ZV
-		// pos.X = x
-		// pos.Y = y
-		// Squares = append(Squares, pos)
+		ray.Length = i
+		if TerrainMap.BlocksVision(vec.X, vec.Y) {
+			ray.Blocked = true
+			break
+		}
+		x, y := snapToCell(vec.X, vec.Y)
+		proximity := 1 - float64(i)/float64(a.viewingDistance+1)
 		food := FoodBlocks[HashCoords(x, y)]
-		if food != nil && food.fp > 0 {
-			a.brain.SetVisionInput(idx, 1-float64(i)/float64(a.viewingDistance+1))
-			return
+		if !seenPlant && food != nil && food.fp > 0 {
+			a.brain.SetVisionInput(idx, proximity)
+			seenPlant = true
+			ray.Plant = i
 		}
+		corpse := Corpses[HashCoords(x, y)]
+		if !seenMeat && corpse != nil && corpse.meat > 0 {
+			a.brain.SetVisionInput(a.fovRays+idx, proximity)
+			seenMeat = true
+			ray.Meat = i
+		}
+	}
+	a.rays = append(a.rays, ray)
+	if !seenPlant {
+		a.brain.SetVisionInput(idx, 1000_000_000)
 	}
-	a.brain.SetVisionInput(idx, 1000_000_000)
+	if !seenMeat {
+		a.brain.SetVisionInput(a.fovRays+idx, 1000_000_000)
+	}
+}
+
+func (a *Animal) Rays() []Ray {
+	return slices.Clone(a.rays)
+}
+
+// Animals look around from their position, where their sprite is drawn.
+func (a *Animal) eyes() (x, y float64) {
+	return a.x, a.y
+}
+
+// Where a ray of the animal is after the given number of steps.
+func (a *Animal) RayPoint(r Ray, step int) (x, y float64) {
+	x, y = a.eyes()
+	d := float64(step) * a.w / 2
+	return x + math.Cos(r.Theta)*d, y + math.Sin(r.Theta)*d
 }
 
 func (a *Animal) GetHP() int {
 	return a.hp
 }
 
+func (a *Animal) IsEating() bool {
+	return a.eating
+}
+
+func (a *Animal) mutateBiteRate() {
+	if Rng.Float64() < 0.2 {
+		a.biteRate = max(0.02, min(a.biteRate+Rng.NormFloat64()*0.02, Game.Plants.YieldPerTick))
+	}
+}
+
 func (a *Animal) CheckNHandlePlantCollisions() {
-	if IsCellFull(a.x, a.y) {
-		sx := a.x - math.Mod(a.x, 16.0)
-		sy := a.y - math.Mod(a.y, 16.0)
-		food := FoodBlocks[HashCoords(sx, sy)]
-		if food.Eeat() > 0 {
-			a.ticksUntilHurt += 100
-			a.hp++
-		}
-	} else if IsCellFull(a.x+16, a.y) {
-		sx := a.x + 16 - math.Mod(a.x, 16.0)
-		sy := a.y - math.Mod(a.y, 16.0)
-		food := FoodBlocks[HashCoords(sx, sy)]
-		if food.Eeat() > 0 {
-			a.ticksUntilHurt += 100
-			a.hp++
-		}
-	} else if IsCellFull(a.x, a.y+16) {
-		sx := a.x - math.Mod(a.x, 16.0)
-		sy := a.y + 16 - math.Mod(a.y, 16.0)
-		food := FoodBlocks[HashCoords(sx, sy)]
-		if food.Eeat() > 0 {
-			a.ticksUntilHurt += 100
-			a.hp++
-		}
-	} else if IsCellFull(a.x+16, a.y+16) {
-		sx := a.x + 16 - math.Mod(a.x, 16.0)
-		sy := a.y + 16 - math.Mod(a.y, 16.0)
-		food := FoodBlocks[HashCoords(sx, sy)]
-		if food.Eeat() > 0 {
-			a.ticksUntilHurt += 100
-			a.hp++
+	for _, d := range [][2]float64{{0, 0}, {16, 0}, {0, 16}, {16, 16}} {
+		x, y := snapToCell(a.x+d[0], a.y+d[1])
+		food := FoodBlocks[HashCoords(x, y)]
+		if food != nil && food.fp > 0 {
+			eaten := food.Bite(a.biteRate)
+			a.digest(PLANT, eaten)
+			if eaten > 0 {
+				a.eating = true
+				a.meal = PLANT
+			}
+			return
 		}
 	}
 }
 
-// func (a *Animal) CheckNHandlePreyCollisions() {
-// 	for _, other := range Animals {
-// 		x, y := other.GetPos()
-// 		w, h := other.GetDim()
-// 		if a.Collides(x, y, w, h) {
-// 			a.hp += other.GetEaten()
-// 			a.ticksUntilHurt += 100
-// 		}
-// 	}
-// }
-
-// func (a *Animal) GetEaten() int {
-// 	a.mu.Lock()
-// 	defer a.mu.Unlock()
-
-// 	if a.hp > 0 {
-// 		a.hp = 0
-// 		return 1
-// 	}
-
-// 	return 0
-// }
-
 func (a *Animal) Collides(x2, y2, w2, h2 float64) bool {
 	x1 := a.x
 	y1 := a.y
 	w1 := a.w
 	h1 := a.h
+	x2, y2 = nearestImage(x1, y1, x2, y2)
 
 	if x1+w1 > x2 && x1 < x2+w2 && y1+h1 > y2 && y1 < y2+h2 {
 		return true
@@ -327,7 +402,81 @@ func (a *Animal) Collides(x2, y2, w2, h2 float64) bool {
 	return false
 }
 
+var nextAnimalId = 1
+
+func newAnimalId() int {
+	id := nextAnimalId
+	nextAnimalId++
+	return id
+}
+
+func (a *Animal) GetId() int {
+	return a.id
+}
+
+func (a *Animal) GetParentId() int {
+	return a.parentId
+}
+
+func (a *Animal) GetGeneration() int {
+	return a.generation
+}
+
+func (a *Animal) GetAge() int {
+	return Ticks - a.birthTick
+}
+
+// Queues a mutated copy of the animal, it joins the world once
+// ticksToAppear runs out.
+func (a *Animal) spawnChild(highVariability bool) *Animal {
+	newAnimal := a.Copy()
+	newAnimal.id = newAnimalId()
+	newAnimal.parentId = a.id
+	newAnimal.generation = a.generation + 1
+	newAnimal.birthTick = Ticks
+	newAnimal.TurnDelta(Rng.NormFloat64() * math.Pi)
+	newAnimal.hp = Game.Animals.Hp
+	newAnimal.ticksUntilHurt = Game.Animals.NewbornTicksUntilHurt
+	newAnimal.ticksToAppear = Game.TicksPerSecond + 1
+	newAnimal.fitness = 0
+	newAnimal.fitnessGoal = a.fitness
+	newAnimal.lifetime = 0
+	newAnimal.foodEaten = 0
+	newAnimal.offspring = 0
+	newAnimal.distance = 0
+	newAnimal.kills = 0
+	newAnimal.eating = false
+	newAnimal.killedBy = 0
+	newAnimal.meatEaten = 0
+	if highVariability {
+		newAnimal.brain.MutateHighVariability()
+	} else {
+		newAnimal.brain.Mutate()
+		newAnimal.diet.Mutate()
+		newAnimal.mutateBiteRate()
+	}
+
+	a.offspring++
+	newAnimals = append(newAnimals, newAnimal)
+	Lineage.recordBirth(newAnimal)
+	newAnimal.assignSpecies(a.species)
+	TotalBirths++
+
+	emit(Event{Type: BIRTH, Animal: newAnimal.id, Parents: []int{a.id}, X: a.x, Y: a.y})
+	emit(Event{Type: MUTATION, Animal: newAnimal.id, Mutation: &MutationSummary{
+		NeuronsDelta:    newAnimal.brain.GetNumberOfNeurons() - a.brain.GetNumberOfNeurons(),
+		LinksDelta:      newAnimal.brain.GetNumberOfLinks() - a.brain.GetNumberOfLinks(),
+		PlantEfficiency: newAnimal.diet.plantEfficiency - a.diet.plantEfficiency,
+		MeatEfficiency:  newAnimal.diet.meatEfficiency - a.diet.meatEfficiency,
+		BiteRate:        newAnimal.biteRate - a.biteRate,
+	}})
+
+	return newAnimal
+}
+
 func (a Animal) Copy() *Animal {
+	a.brain = a.brain.Copy()
+	a.rays = nil
 	return &a
 }
 
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"fmt"
+
+	"example.com/artificial-life/neat"
+)
+
+// A small controlled world where brains are evaluated on their own, e.g. to
+// pre-train them with a neat.Population before seeding the open world.
+type Arena struct {
+	Config GameConfig
+	Ticks  int
+	// Copies of the brain living in the arena at the same time.
+	Animals int
+	// Every brain is evaluated once in the world grown from each seed, the
+	// fitness being the average.
+	Seeds []uint64
+}
+
+func DefaultArena() Arena {
+	config := DefaultConfig()
+	config.WorldSize = 1024
+	config.InitialAnimalChance = 0
+	config.Population.Prey.Min = 0
+	config.Population.Hunters.Min = 0
+	return Arena{Config: config, Ticks: 60 * 60, Animals: 4, Seeds: []uint64{1, 2, 3}}
+}
+
+func (a *Animal) SetBrain(brain *neat.Genome) error {
+	if brain.GetNumberOfInputs() != 2*a.fovRays+1 || brain.GetNumberOfOutputs() != 3 {
+		return fmt.Errorf("brain has %d inputs and %d outputs, expected %d and 3",
+			brain.GetNumberOfInputs(), brain.GetNumberOfOutputs(), 2*a.fovRays+1)
+	}
+	a.leaveSpecies()
+	a.brain = brain
+	a.assignSpecies(0)
+	return nil
+}
+
+// Gives every animal of the world a mutated copy of the brain.
+func SeedBrains(brain *neat.Genome) error {
+	for _, a := range Animals {
+		b := brain.Copy()
+		b.Mutate()
+		if err := a.SetBrain(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Positions along the way an animal's trajectory is summed up by.
+const BEHAVIOR_SAMPLES = 8
+
+// Average fitness of the copies of the brain in the arena. Evaluating
+// replaces the world, but leaves the random number generator of the neat
+// package, which the trainer breeds with, as it was.
+func (arena Arena) Evaluate(brain *neat.Genome) float64 {
+	fitness, _ := arena.EvaluateBehavior(brain)
+	return fitness
+}
+
+// Like Evaluate, also describing what every copy of the brain did: where it
+// was at BEHAVIOR_SAMPLES moments evenly spread over the evaluation, when it
+// first ate and how much of the time it spent eating, all scaled to [0, 1].
+// Copies are spawned at the same places whatever the brain, so behaviours
+// of different brains can be compared value by value.
+func (arena Arena) EvaluateBehavior(brain *neat.Genome) (float64, []float64) {
+	defer neat.SetRng(neat.Rng)
+
+	total := 0.0
+	var behavior []float64
+	for _, seed := range arena.Seeds {
+		Seed(seed)
+		if err := InitWorld(arena.Config); err != nil {
+			panic(err)
+		}
+
+		var evaluated []*Animal
+		for len(evaluated) < arena.Animals {
+			x := Rng.Float64() * Game.WorldSize
+			y := Rng.Float64() * Game.WorldSize
+			if !TerrainMap.IsPassable(x, y) {
+				continue
+			}
+			a, err := spawnAnimal(x, y, PREY)
+			if err != nil {
+				continue
+			}
+			if err := a.SetBrain(brain.Copy()); err != nil {
+				panic(err)
+			}
+			evaluated = append(evaluated, a)
+		}
+
+		trajectories := make([][]float64, len(evaluated))
+		firstMeal := make([]int, len(evaluated))
+		eatingTicks := make([]int, len(evaluated))
+		for i := range firstMeal {
+			firstMeal[i] = arena.Ticks
+		}
+		samples := 0
+		sample := func() {
+			samples++
+			for i, a := range evaluated {
+				trajectories[i] = append(trajectories[i], a.x/Game.WorldSize, a.y/Game.WorldSize)
+			}
+		}
+
+		for Ticks < arena.Ticks {
+			alive := false
+			for _, a := range evaluated {
+				alive = alive || a.hp > 0
+			}
+			if !alive {
+				break
+			}
+			Tick()
+
+			for i, a := range evaluated {
+				if a.hp > 0 && a.eating {
+					firstMeal[i] = min(firstMeal[i], Ticks)
+					eatingTicks[i]++
+				}
+			}
+			if Ticks%max(1, arena.Ticks/BEHAVIOR_SAMPLES) == 0 && samples < BEHAVIOR_SAMPLES {
+				sample()
+			}
+		}
+		// The dead stay where they died.
+		for samples < BEHAVIOR_SAMPLES {
+			sample()
+		}
+
+		for i, a := range evaluated {
+			total += a.fitness
+			behavior = append(behavior, trajectories[i]...)
+			behavior = append(behavior,
+				float64(firstMeal[i])/float64(arena.Ticks),
+				float64(eatingTicks[i])/float64(arena.Ticks))
+		}
+	}
+	return total / float64(max(1, len(arena.Seeds)*arena.Animals)), behavior
+}
@@ -0,0 +1,272 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const CHECKPOINT_HEADER = "alife-checkpoint sha256="
+
+type AutosavePolicy struct {
+	Dir        string
+	EveryTicks int
+	Every      time.Duration
+	Keep       int
+}
+
+type Autosaver struct {
+	policy   AutosavePolicy
+	lastTick int
+	lastTime time.Time
+}
+
+func NewAutosaver(policy AutosavePolicy) *Autosaver {
+	return &Autosaver{policy: policy, lastTick: Ticks, lastTime: time.Now()}
+}
+
+// Meant to be called after every tick, it writes a checkpoint whenever
+// either of the policy's periods has gone by.
+func (s *Autosaver) Update() error {
+	due := s.policy.EveryTicks > 0 && Ticks-s.lastTick >= s.policy.EveryTicks
+	due = due || s.policy.Every > 0 && time.Since(s.lastTime) >= s.policy.Every
+	if !due {
+		return nil
+	}
+
+	s.lastTick = Ticks
+	s.lastTime = time.Now()
+	if _, err := WriteCheckpoint(s.policy.Dir); err != nil {
+		return err
+	}
+	return PruneCheckpoints(s.policy.Dir, s.policy.Keep)
+}
+
+// Named after their run, so that runs sharing a directory don't overwrite
+// each other's checkpoints, then after their tick.
+func checkpointName(run string, ticks int) string {
+	return fmt.Sprintf("checkpoint-%s-%012d.json", run, ticks)
+}
+
+func checkpointRun(path string) string {
+	run, _, _ := strings.Cut(strings.TrimPrefix(filepath.Base(path), "checkpoint-"), "-")
+	return run
+}
+
+// Writes the current world to dir. The file only shows up under its final
+// name once it has been completely written, so a crash never leaves a half
+// written checkpoint behind.
+func WriteCheckpoint(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	var payload bytes.Buffer
+	if err := SaveWorld(&payload); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload.Bytes())
+
+	tmp, err := os.CreateTemp(dir, ".checkpoint-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = fmt.Fprintf(tmp, "%s%s\n", CHECKPOINT_HEADER, hex.EncodeToString(sum[:]))
+	if err == nil {
+		_, err = tmp.Write(payload.Bytes())
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, checkpointName(RunId, Ticks))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	// The rename only survives a crash once the directory itself is synced.
+	return path, syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Checkpoints of the run in dir, oldest first, or of every run, run by run,
+// when run is empty.
+func ListCheckpoints(dir, run string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() || !strings.HasPrefix(name, "checkpoint-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if run == "" || checkpointRun(path) == run {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// Removes the oldest checkpoints of the current run but its first one,
+// which replays start from, leaving the other runs' alone.
+func PruneCheckpoints(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	paths, err := ListCheckpoints(dir, RunId)
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	paths = paths[1:]
+	for len(paths) > keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// Writes the first checkpoint of the current run to dir, unless it has
+// one already.
+func WriteFirstCheckpoint(dir string) error {
+	paths, err := ListCheckpoints(dir, RunId)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(paths) > 0 {
+		return nil
+	}
+	_, err = WriteCheckpoint(dir)
+	return err
+}
+
+func readCheckpoint(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("%s: missing checkpoint header", path)
+	}
+	expected, ok := strings.CutPrefix(strings.TrimSpace(header), CHECKPOINT_HEADER)
+	if !ok {
+		return nil, fmt.Errorf("%s: not a checkpoint", path)
+	}
+
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(payload)
+	if hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf("%s: checksum mismatch, the checkpoint is corrupted", path)
+	}
+
+	s, err := decodeSnapshot(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Whether the file starts with a checkpoint header, rather than being a
+// bare snapshot.
+func IsCheckpoint(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(CHECKPOINT_HEADER))
+	if _, err := io.ReadFull(file, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(header) == CHECKPOINT_HEADER, nil
+}
+
+func LoadCheckpoint(path string) error {
+	s, err := readCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	return RestoreSnapshot(s)
+}
+
+// Restores the newest checkpoint which is not corrupted of the run that
+// wrote to dir last, returning its path.
+func ResumeLatestCheckpoint(dir string) (string, error) {
+	paths, err := ListCheckpoints(dir, "")
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestTime time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil && (latest == "" || info.ModTime().After(latestTime)) {
+			latest, latestTime = path, info.ModTime()
+		}
+	}
+	paths = slices.DeleteFunc(paths, func(path string) bool {
+		return checkpointRun(path) != checkpointRun(latest)
+	})
+
+	var errs []error
+	for i := len(paths) - 1; i >= 0; i-- {
+		s, err := readCheckpoint(paths[i])
+		if err == nil {
+			err = RestoreSnapshot(s)
+		}
+		if err == nil {
+			return paths[i], nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return "", fmt.Errorf("no checkpoints in %s", dir)
+	}
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,327 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"example.com/artificial-life/neat"
+)
+
+// Every parameter of a world. It is stored in snapshots, so a run always
+// records how it was set up.
+type GameConfig struct {
+	TicksPerSecond int      `json:"ticksPerSecond"`
+	WorldSize      float64  `json:"worldSize"`
+	Topology       Topology `json:"topology"`
+	// An image of the map, one pixel per tile, used instead of a generated
+	// one. It must be worldSize/32 pixels wide and high.
+	TerrainFile string `json:"terrainFile"`
+
+	// Chances of a cell starting with a plant, scaled by its fertility, or
+	// with an animal.
+	InitialPlantChance  float64 `json:"initialPlantChance"`
+	InitialAnimalChance float64 `json:"initialAnimalChance"`
+	HunterRatio         float64 `json:"hunterRatio"`
+
+	CorpseDecayPeriod int     `json:"corpseDecayPeriod"`
+	SpeciesThreshold  float64 `json:"speciesThreshold"`
+
+	Animals    AnimalConfig       `json:"animals"`
+	Plants     PlantConfig        `json:"plants"`
+	Population PopulationControls `json:"population"`
+	Fitness    FitnessConfig      `json:"fitness"`
+	Recovery   RecoveryConfig     `json:"recovery"`
+	Mutation   neat.MutationRates `json:"mutation"`
+}
+
+type AnimalConfig struct {
+	Hp int `json:"hp"`
+	// Ticks between two hp lost to hunger, newborns start with a longer
+	// grace period.
+	HungerPeriod          int     `json:"hungerPeriod"`
+	NewbornTicksUntilHurt int     `json:"newbornTicksUntilHurt"`
+	Speed                 float64 `json:"speed"`
+	TurningRate           float64 `json:"turningRate"`
+	FovRays               int     `json:"fovRays"`
+	FovDegrees            float64 `json:"fovDegrees"`
+	ViewingDistance       int     `json:"viewingDistance"`
+	BiteRate              float64 `json:"biteRate"`
+	// Animals with more ticks of food stored than the threshold reproduce,
+	// fit enough ones have twins.
+	ReproductionThreshold int     `json:"reproductionThreshold"`
+	ReproductionCooldown  int     `json:"reproductionCooldown"`
+	TwinsFitness          float64 `json:"twinsFitness"`
+}
+
+type PlantConfig struct {
+	FertileZones  int     `json:"fertileZones"`
+	BaseFertility float64 `json:"baseFertility"`
+	GrowthPeriod  int     `json:"growthPeriod"`
+	SpawnPeriod   int     `json:"spawnPeriod"`
+	SpawnGroup    int     `json:"spawnGroup"`
+	SeedChance    float64 `json:"seedChance"`
+	WitherChance  float64 `json:"witherChance"`
+	SeasonLength  int     `json:"seasonLength"`
+	MaxFp         float64 `json:"maxFp"`
+	// Fp a plant gives away per tick, however many animals bite it.
+	YieldPerTick float64 `json:"yieldPerTick"`
+}
+
+func DefaultConfig() GameConfig {
+	return GameConfig{
+		TicksPerSecond:      60,
+		WorldSize:           4096,
+		Topology:            WALLS,
+		InitialPlantChance:  0.2,
+		InitialAnimalChance: 0.005,
+		HunterRatio:         0,
+		CorpseDecayPeriod:   60,
+		SpeciesThreshold:    1.0,
+		Animals: AnimalConfig{
+			Hp:                    20,
+			HungerPeriod:          20,
+			NewbornTicksUntilHurt: 100,
+			Speed:                 60,
+			TurningRate:           0.125,
+			FovRays:               6,
+			FovDegrees:            90,
+			ViewingDistance:       50,
+			BiteRate:              0.1,
+			ReproductionThreshold: 20 * 10,
+			ReproductionCooldown:  20 * 10,
+			TwinsFitness:          20 * 40,
+		},
+		Plants: PlantConfig{
+			FertileZones:  12,
+			BaseFertility: 0.1,
+			GrowthPeriod:  120,
+			SpawnPeriod:   600,
+			SpawnGroup:    6,
+			SeedChance:    0.002,
+			WitherChance:  0.001,
+			SeasonLength:  60 * 60 * 2,
+			MaxFp:         10,
+			YieldPerTick:  0.25,
+		},
+		Population: PopulationControls{
+			Prey:    PopulationControl{Min: 5, Immigrants: RANDOM},
+			Hunters: PopulationControl{Min: 5, Immigrants: RANDOM},
+		},
+		Fitness: FitnessConfig{
+			Function:    LIFETIME,
+			Weights:     FitnessWeights{Lifetime: 1},
+			InitialGoal: 60 * 30,
+		},
+		Recovery: RecoveryConfig{
+			Policy:         MUTANT,
+			HallOfFameSize: 20,
+		},
+		Mutation: neat.DefaultMutationRates(),
+	}
+}
+
+func (c GameConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	probability := func(name string, p float64) {
+		check(p >= 0 && p <= 1, "%s must be between 0 and 1, got %g", name, p)
+	}
+	positive := func(name string, v float64) {
+		check(v > 0, "%s must be positive, got %g", name, v)
+	}
+
+	positive("ticksPerSecond", float64(c.TicksPerSecond))
+	check(c.WorldSize >= TILE_SIZE, "worldSize must be at least %g, got %g", TILE_SIZE, c.WorldSize)
+	check(c.Topology <= SOFT, "unknown topology %d", c.Topology)
+	probability("initialPlantChance", c.InitialPlantChance)
+	probability("initialAnimalChance", c.InitialAnimalChance)
+	probability("hunterRatio", c.HunterRatio)
+	positive("corpseDecayPeriod", float64(c.CorpseDecayPeriod))
+	check(c.SpeciesThreshold >= 0, "speciesThreshold can't be negative, got %g", c.SpeciesThreshold)
+
+	a := c.Animals
+	positive("animals.hp", float64(a.Hp))
+	positive("animals.hungerPeriod", float64(a.HungerPeriod))
+	positive("animals.newbornTicksUntilHurt", float64(a.NewbornTicksUntilHurt))
+	check(a.Speed >= 0, "animals.speed can't be negative, got %g", a.Speed)
+	check(a.TurningRate >= 0, "animals.turningRate can't be negative, got %g", a.TurningRate)
+	check(a.FovRays >= 2, "animals.fovRays must be at least 2, got %d", a.FovRays)
+	check(a.FovDegrees > 0 && a.FovDegrees <= 360, "animals.fovDegrees must be between 0 and 360, got %g", a.FovDegrees)
+	positive("animals.viewingDistance", float64(a.ViewingDistance))
+	check(a.BiteRate > 0 && a.BiteRate <= c.Plants.YieldPerTick,
+		"animals.biteRate must be positive and at most plants.yieldPerTick, got %g", a.BiteRate)
+	positive("animals.reproductionThreshold", float64(a.ReproductionThreshold))
+	check(a.ReproductionCooldown >= 0, "animals.reproductionCooldown can't be negative, got %d", a.ReproductionCooldown)
+	check(a.TwinsFitness >= 0, "animals.twinsFitness can't be negative, got %g", a.TwinsFitness)
+
+	p := c.Plants
+	check(p.FertileZones >= 0, "plants.fertileZones can't be negative, got %d", p.FertileZones)
+	probability("plants.baseFertility", p.BaseFertility)
+	positive("plants.growthPeriod", float64(p.GrowthPeriod))
+	positive("plants.spawnPeriod", float64(p.SpawnPeriod))
+	check(p.SpawnGroup >= 0, "plants.spawnGroup can't be negative, got %d", p.SpawnGroup)
+	probability("plants.seedChance", p.SeedChance)
+	probability("plants.witherChance", p.WitherChance)
+	positive("plants.seasonLength", float64(p.SeasonLength))
+	check(p.MaxFp >= 1, "plants.maxFp must be at least 1, got %g", p.MaxFp)
+	positive("plants.yieldPerTick", p.YieldPerTick)
+
+	f := c.Fitness
+	check(f.Function <= WEIGHTED, "unknown fitness function %d", f.Function)
+	check(f.GoalRatio >= 0, "fitness.goalRatio can't be negative, got %g", f.GoalRatio)
+	check(f.InitialGoal >= 0, "fitness.initialGoal can't be negative, got %g", f.InitialGoal)
+
+	c.Population.Prey.validate("population.prey", check)
+	c.Population.Hunters.validate("population.hunters", check)
+
+	r := c.Recovery
+	check(r.Policy <= RANDOM, "unknown recovery policy %d", r.Policy)
+	usesFile := r.Policy == FILE || c.Population.Prey.Immigrants == FILE || c.Population.Hunters.Immigrants == FILE
+	check(!usesFile || r.File != "", "recovery.file must be set for the file policy")
+	check(r.HallOfFameSize >= 0, "recovery.hallOfFameSize can't be negative, got %d", r.HallOfFameSize)
+
+	m := c.Mutation
+	probability("mutation.weight", m.Weight)
+	check(m.WeightPower >= 0, "mutation.weightPower can't be negative, got %g", m.WeightPower)
+	probability("mutation.toggleLink", m.ToggleLink)
+	probability("mutation.addLink", m.AddLink)
+	probability("mutation.removeLink", m.RemoveLink)
+	probability("mutation.addNeuron", m.AddNeuron)
+	probability("mutation.removeNeuron", m.RemoveNeuron)
+
+	return errors.Join(errs...)
+}
+
+func (t Topology) String() string {
+	switch t {
+	case WALLS:
+		return "walls"
+	case TORUS:
+		return "torus"
+	case SOFT:
+		return "soft"
+	}
+	return fmt.Sprintf("Topology(%d)", t)
+}
+
+func (t Topology) MarshalText() ([]byte, error) {
+	if t > SOFT {
+		return nil, fmt.Errorf("unknown topology %d", t)
+	}
+	return []byte(t.String()), nil
+}
+
+func (t *Topology) UnmarshalText(text []byte) error {
+	for _, topology := range []Topology{WALLS, TORUS, SOFT} {
+		if string(text) == topology.String() {
+			*t = topology
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown topology %q, expected walls, torus or soft", text)
+}
+
+// Changes the parameters of a running world. Its size and terrain were
+// laid out when it was created, they can't change.
+func Reconfigure(config GameConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.WorldSize != Game.WorldSize || config.TerrainFile != Game.TerrainFile {
+		return errors.New("worldSize and terrainFile can't change once a world exists")
+	}
+	recovery, err := readRecoveryFile(config)
+	if err != nil {
+		return err
+	}
+	applyConfig(config, recovery)
+	return nil
+}
+
+func applyConfig(config GameConfig, recovery *HallOfFame) {
+	Game = config
+	neat.SetMutationRates(Game.Mutation)
+	Fitness = Game.Fitness.Func()
+	recoveryGenomes = recovery
+}
+
+// Decodes the configuration over the defaults, so that only the parameters
+// which change need to be written down. Unknown parameters are an error,
+// they are most likely typos.
+func ReadConfig(r io.Reader) (GameConfig, error) {
+	return ReadConfigOver(DefaultConfig(), r)
+}
+
+// Reads a config in which missing parameters keep their value in base.
+func ReadConfigOver(base GameConfig, r io.Reader) (GameConfig, error) {
+	c := base
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return c, err
+	}
+	return c, c.Validate()
+}
+
+func LoadConfigFile(path string) (GameConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return GameConfig{}, err
+	}
+	defer file.Close()
+
+	c, err := ReadConfig(file)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c GameConfig) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// Sets a single parameter from its dotted JSON name, e.g.
+// "animals.speed=80" or "topology=torus". Values which aren't valid JSON
+// are taken as strings.
+func (c *GameConfig) Set(assignment string) error {
+	key, value, ok := strings.Cut(assignment, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", assignment)
+	}
+
+	raw := json.RawMessage(value)
+	if !json.Valid(raw) {
+		quoted, _ := json.Marshal(value)
+		raw = quoted
+	}
+
+	// Wrap the value in objects until it reaches the top level, then decode
+	// it over the current configuration.
+	path := strings.Split(key, ".")
+	for i := len(path) - 1; i >= 0; i-- {
+		wrapped, err := json.Marshal(map[string]json.RawMessage{path[i]: raw})
+		if err != nil {
+			return err
+		}
+		raw = wrapped
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
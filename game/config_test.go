@@ -0,0 +1,122 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigSet(t *testing.T) {
+	tests := []struct {
+		assignment string
+		check      func(c GameConfig) bool
+	}{
+		{"animals.speed=80", func(c GameConfig) bool { return c.Animals.Speed == 80 }},
+		{"worldSize=2048", func(c GameConfig) bool { return c.WorldSize == 2048 }},
+		{"topology=torus", func(c GameConfig) bool { return c.Topology == TORUS }},
+		{`topology="soft"`, func(c GameConfig) bool { return c.Topology == SOFT }},
+		{"population.hunters.min=3", func(c GameConfig) bool { return c.Population.Hunters.Min == 3 && c.Population.Prey.Min == 5 }},
+		{"recovery.policy=hallOfFame", func(c GameConfig) bool { return c.Recovery.Policy == HALL_OF_FAME }},
+		{"mutation.addNeuron=0.5", func(c GameConfig) bool { return c.Mutation.AddNeuron == 0.5 && c.Mutation.AddLink == 0.2 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assignment, func(t *testing.T) {
+			c := DefaultConfig()
+			if err := c.Set(tt.assignment); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(c) {
+				t.Errorf("%s wasn't applied", tt.assignment)
+			}
+			if err := c.Validate(); err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigSetRejects(t *testing.T) {
+	tests := []struct {
+		assignment string
+		want       string
+	}{
+		{"animals.speed", "expected key=value"},
+		{"animals.sped=80", "unknown field"},
+		{"nothing=1", "unknown field"},
+		{"animals.speed=fast", "animals.speed"},
+		{"topology=sphere", "unknown topology"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assignment, func(t *testing.T) {
+			c := DefaultConfig()
+			err := c.Set(tt.assignment)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Set(%q) = %v, want an error mentioning %q", tt.assignment, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		want  string
+		check func(c GameConfig) bool
+	}{
+		{"missing parameters take their default", `{"animals": {"hp": 30}}`, "", func(c GameConfig) bool {
+			return c.Animals.Hp == 30 && c.Animals.Speed == DefaultConfig().Animals.Speed
+		}},
+		{"unknown parameters", `{"animals": {"hitPoints": 30}}`, "unknown field", nil},
+		{"invalid parameters", `{"animals": {"hp": 0}}`, "animals.hp must be positive", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ReadConfig(strings.NewReader(tt.json))
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("ReadConfig() = %v, want an error mentioning %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(c) {
+				t.Errorf("unexpected config %+v", c)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *GameConfig)
+		want   string
+	}{
+		{"defaults", func(c *GameConfig) {}, ""},
+		{"small world", func(c *GameConfig) { c.WorldSize = 16 }, "worldSize must be at least"},
+		{"probability", func(c *GameConfig) { c.HunterRatio = 1.5 }, "hunterRatio must be between 0 and 1"},
+		{"too few rays", func(c *GameConfig) { c.Animals.FovRays = 1 }, "animals.fovRays must be at least 2"},
+		{"bite above yield", func(c *GameConfig) { c.Animals.BiteRate = 1 }, "animals.biteRate"},
+		{"maximum below minimum", func(c *GameConfig) { c.Population.Prey.Max = 2 }, "population.prey.max must be at least"},
+		{"file policy without a file", func(c *GameConfig) { c.Population.Hunters.Immigrants = FILE }, "recovery.file must be set"},
+		{"later errors are reported too", func(c *GameConfig) { c.Animals.Hp = 0; c.Plants.MaxFp = 0 }, "plants.maxFp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.change(&c)
+			err := c.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
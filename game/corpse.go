@@ -0,0 +1,114 @@
+package game
+
+import (
+	"sync"
+
+	"github.com/gopxl/pixel/v2"
+)
+
+type Corpse struct {
+	x            float64
+	y            float64
+	w            float64
+	h            float64
+	meat         int
+	maxMeat      int
+	ticksToDecay int
+	animalType   AnimalType
+	sprite       *pixel.Sprite
+	mu           sync.Mutex
+}
+
+var Corpses = make(map[int]*Corpse)
+
+func LeaveCorpse(a *Animal) {
+	meat := a.meatPoints() - a.meatEaten
+	if meat <= 0 {
+		return
+	}
+	x, y := snapToCell(a.x+a.w/2, a.y+a.h/2)
+	key := HashCoords(x, y)
+
+	if corpse := Corpses[key]; corpse != nil {
+		corpse.meat += meat
+		corpse.maxMeat += meat
+		return
+	}
+
+	Corpses[key] = &Corpse{
+		x:            x,
+		y:            y,
+		w:            a.w,
+		h:            a.h,
+		meat:         meat,
+		maxMeat:      meat,
+		ticksToDecay: Game.CorpseDecayPeriod,
+		animalType:   a.animalType,
+		sprite:       a.sprite,
+	}
+}
+
+func (c *Corpse) GetPos() (x, y float64) {
+	return c.x, c.y
+}
+
+func (c *Corpse) GetDim() (w, h float64) {
+	return c.w, c.h
+}
+
+func (c *Corpse) GetSprite() *pixel.Sprite {
+	return c.sprite
+}
+
+func (c *Corpse) GetFreshness() float64 {
+	return float64(c.meat) / float64(c.maxMeat)
+}
+
+func (c *Corpse) Eeat() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.meat > 0 {
+		c.meat--
+		return 1
+	}
+
+	return 0
+}
+
+func DecayCorpses() {
+	for key, corpse := range Corpses {
+		corpse.ticksToDecay--
+		if corpse.ticksToDecay <= 0 {
+			corpse.meat--
+			corpse.ticksToDecay = Game.CorpseDecayPeriod
+		}
+		if corpse.meat <= 0 {
+			delete(Corpses, key)
+		}
+	}
+}
+
+func (a *Animal) CheckNHandleCorpseCollisions() {
+	if !a.diet.IsCarnivore() {
+		return
+	}
+
+	for _, d := range [][2]float64{{0, 0}, {16, 0}, {0, 16}, {16, 16}} {
+		x, y := snapToCell(a.x+d[0], a.y+d[1])
+		corpse := Corpses[HashCoords(x, y)]
+		if corpse != nil {
+			eaten := corpse.Eeat()
+			a.digest(MEAT, float64(eaten))
+			if eaten > 0 {
+				a.eating = true
+				a.meal = MEAT
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package game
+
+import "math"
+
+type FoodType uint8
+
+const (
+	PLANT FoodType = iota
+	MEAT
+)
+
+const (
+	// An animal can't be very good at digesting both plants and meat: the
+	// sum of both efficiencies is never allowed to go above this cap.
+	DIET_EFFICIENCY_CAP = 1.0
+	MEAT_AREA_PER_POINT = 16.0
+	// Share of a prey's meat points its killer eats on the spot, the rest
+	// being left in its corpse.
+	KILL_MEAT_SHARE = 0.25
+)
+
+// Energy (ticks until hurt) and hp restored by a single food point when
+// digested with perfect efficiency.
+var foodEnergy = [...]float64{PLANT: 110, MEAT: 25}
+var foodHp = [...]float64{PLANT: 1.1, MEAT: 0.25}
+
+type Diet struct {
+	plantEfficiency float64
+	meatEfficiency  float64
+}
+
+func InitDiet(animalType AnimalType) Diet {
+	var d Diet
+	if animalType == PREY {
+		d = Diet{plantEfficiency: 0.9, meatEfficiency: 0.1}
+	} else {
+		d = Diet{plantEfficiency: 0.1, meatEfficiency: 0.9}
+	}
+	return d
+}
+
+func (d *Diet) constrain() {
+	d.plantEfficiency = max(0, min(d.plantEfficiency, 1))
+	d.meatEfficiency = max(0, min(d.meatEfficiency, 1))
+
+	total := d.plantEfficiency + d.meatEfficiency
+	if total > DIET_EFFICIENCY_CAP {
+		d.plantEfficiency *= DIET_EFFICIENCY_CAP / total
+		d.meatEfficiency *= DIET_EFFICIENCY_CAP / total
+	}
+}
+
+func (d *Diet) Mutate() {
+	if Rng.Float64() < 0.2 {
+		d.plantEfficiency += Rng.NormFloat64() * 0.05
+	}
+	if Rng.Float64() < 0.2 {
+		d.meatEfficiency += Rng.NormFloat64() * 0.05
+	}
+	d.constrain()
+}
+
+func (d Diet) Efficiency(foodType FoodType) float64 {
+	if foodType == MEAT {
+		return d.meatEfficiency
+	}
+	return d.plantEfficiency
+}
+
+func (d Diet) IsCarnivore() bool {
+	return d.meatEfficiency > d.plantEfficiency
+}
+
+func (a *Animal) GetDiet() Diet {
+	return a.diet
+}
+
+func (a *Animal) digest(foodType FoodType, amount float64) {
+	if amount <= 0 {
+		return
+	}
+
+	a.foodEaten += amount
+	eff := a.diet.Efficiency(foodType)
+	a.ticksUntilHurt += int(math.Round(amount * foodEnergy[foodType] * eff))
+
+	a.hpDigested += amount * foodHp[foodType] * eff
+	for a.hpDigested >= 1 {
+		a.hp++
+		a.hpDigested--
+	}
+}
+
+func (a *Animal) meatPoints() int {
+	return max(1, int(a.w*a.h/MEAT_AREA_PER_POINT))
+}
+
+func (a *Animal) CheckNHandlePreyCollisions() {
+	if !a.diet.IsCarnivore() {
+		return
+	}
+
+	for _, other := range Animals {
+		if other == a || other.diet.IsCarnivore() {
+			continue
+		}
+		x, y := other.GetPos()
+		w, h := other.GetDim()
+		if a.Collides(x, y, w, h) && other.GetKilled(a) {
+			a.kills++
+			emit(Event{Type: KILL, Animal: a.id, Other: other.id, X: x, Y: y})
+
+			// The kill feeds the hunter as much as its diet lets it.
+			other.meatEaten = int(math.Ceil(float64(other.meatPoints()) * KILL_MEAT_SHARE))
+			a.digest(MEAT, float64(other.meatEaten))
+			a.eating = true
+			a.meal = MEAT
+		}
+	}
+}
+
+// The meat of a killed animal its killer didn't eat is left in its corpse
+// once it gets pruned. Animals are updated one after the other, so a prey
+// can't be killed twice at the same time.
+func (a *Animal) GetKilled(killer *Animal) bool {
+	if a.hp > 0 {
+		a.hp = 0
+		a.killedBy = killer.id
+		return true
+	}
+
+	return false
+}
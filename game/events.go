@@ -0,0 +1,145 @@
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+type EventType string
+
+const (
+	BIRTH    EventType = "birth"
+	DEATH    EventType = "death"
+	EAT      EventType = "eat"
+	KILL     EventType = "kill"
+	MATE     EventType = "mate"
+	MUTATION EventType = "mutation"
+	// Population controls stepping in, the cause telling which one.
+	POPULATION EventType = "population"
+	// An intervention from outside the simulation, the cause telling which
+	// one.
+	INTERVENTION EventType = "intervention"
+	// The events that follow belong to the run, which is starting or being
+	// resumed.
+	RUN EventType = "run"
+)
+
+const (
+	CAUSE_STARVATION = "starvation"
+	CAUSE_KILLED     = "killed"
+)
+
+type MutationSummary struct {
+	NeuronsDelta    int     `json:"neuronsDelta"`
+	LinksDelta      int     `json:"linksDelta"`
+	PlantEfficiency float64 `json:"plantEfficiency"`
+	MeatEfficiency  float64 `json:"meatEfficiency"`
+	BiteRate        float64 `json:"biteRate"`
+}
+
+type Event struct {
+	Tick     int       `json:"tick"`
+	Type     EventType `json:"type"`
+	Animal   int       `json:"animal"`
+	Parents  []int     `json:"parents,omitempty"`
+	Children []int     `json:"children,omitempty"`
+	Other    int       `json:"other,omitempty"`
+	Cause    string    `json:"cause,omitempty"`
+	Food     string    `json:"food,omitempty"`
+	// Type and size of the population involved in a population event.
+	AnimalType string           `json:"animalType,omitempty"`
+	Count      int              `json:"count,omitempty"`
+	X          float64          `json:"x"`
+	Y          float64          `json:"y"`
+	Mutation   *MutationSummary `json:"mutation,omitempty"`
+	Run        string           `json:"run,omitempty"`
+	// Parameters and seed a run was given when it was resumed, if any.
+	Config *GameConfig `json:"config,omitempty"`
+	Seed   uint64      `json:"seed,omitempty"`
+}
+
+// Every event happening in the world is handed to EventSink, if any.
+var EventSink func(Event)
+
+func emit(e Event) {
+	if EventSink == nil {
+		return
+	}
+	e.Tick = Ticks
+	EventSink(e)
+}
+
+// Tells the log which run it is recording, so that a replay only uses the
+// checkpoints of that run. A resumed run records the parameters and seed it
+// was given, if any, for replays to give it the same ones.
+func RecordRun(config *GameConfig, seed uint64) {
+	emit(Event{Type: RUN, Run: RunId, Config: config, Seed: seed})
+}
+
+func (t FoodType) String() string {
+	if t == MEAT {
+		return "meat"
+	}
+	return "plant"
+}
+
+// Append only JSON Lines log of events.
+type EventLog struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	err  error
+}
+
+func OpenEventLog(path string) (*EventLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	return &EventLog{file: file, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (l *EventLog) Record(e Event) {
+	if l.err == nil {
+		l.err = l.enc.Encode(e)
+	}
+}
+
+// Returns the first error found while recording since the last flush.
+func (l *EventLog) Flush() error {
+	err := l.err
+	l.err = nil
+	if flushErr := l.w.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func (l *EventLog) Close() error {
+	err := l.Flush()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func ReadEventLog(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []Event
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
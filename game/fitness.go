@@ -0,0 +1,126 @@
+package game
+
+import "fmt"
+
+// Scores how well an animal is doing, higher being better. It is kept in
+// the animal's fitness every tick.
+type FitnessFunc func(a *Animal) float64
+
+// Fitness of the animals of the world. InitWorld sets it from the
+// configuration, set it afterwards to plug in another one.
+var Fitness FitnessFunc = LifetimeFitness
+
+type FitnessKind uint8
+
+const (
+	LIFETIME FitnessKind = iota
+	FOOD
+	OFFSPRING
+	DISTANCE
+	KILLS
+	WEIGHTED
+)
+
+type FitnessWeights struct {
+	Lifetime  float64 `json:"lifetime"`
+	Food      float64 `json:"food"`
+	Offspring float64 `json:"offspring"`
+	Distance  float64 `json:"distance"`
+	Kills     float64 `json:"kills"`
+}
+
+type FitnessConfig struct {
+	Function FitnessKind `json:"function"`
+	// Only used by the weighted function.
+	Weights FitnessWeights `json:"weights"`
+	// Animals only reproduce once their fitness reaches this fraction of
+	// their goal, their parent's fitness when it had them, or InitialGoal
+	// for animals without parents. 0 lets them reproduce regardless.
+	GoalRatio   float64 `json:"goalRatio"`
+	InitialGoal float64 `json:"initialGoal"`
+}
+
+func LifetimeFitness(a *Animal) float64 {
+	return float64(a.lifetime)
+}
+
+func FoodFitness(a *Animal) float64 {
+	return a.foodEaten
+}
+
+func OffspringFitness(a *Animal) float64 {
+	return float64(a.offspring)
+}
+
+func DistanceFitness(a *Animal) float64 {
+	return a.distance
+}
+
+func KillsFitness(a *Animal) float64 {
+	return float64(a.kills)
+}
+
+func WeightedFitness(w FitnessWeights) FitnessFunc {
+	return func(a *Animal) float64 {
+		return w.Lifetime*float64(a.lifetime) +
+			w.Food*a.foodEaten +
+			w.Offspring*float64(a.offspring) +
+			w.Distance*a.distance +
+			w.Kills*float64(a.kills)
+	}
+}
+
+func (c FitnessConfig) Func() FitnessFunc {
+	switch c.Function {
+	case FOOD:
+		return FoodFitness
+	case OFFSPRING:
+		return OffspringFitness
+	case DISTANCE:
+		return DistanceFitness
+	case KILLS:
+		return KillsFitness
+	case WEIGHTED:
+		return WeightedFitness(c.Weights)
+	}
+	return LifetimeFitness
+}
+
+func (a *Animal) reachedFitnessGoal() bool {
+	return a.fitness >= Game.Fitness.GoalRatio*a.fitnessGoal
+}
+
+func (k FitnessKind) String() string {
+	switch k {
+	case LIFETIME:
+		return "lifetime"
+	case FOOD:
+		return "food"
+	case OFFSPRING:
+		return "offspring"
+	case DISTANCE:
+		return "distance"
+	case KILLS:
+		return "kills"
+	case WEIGHTED:
+		return "weighted"
+	}
+	return fmt.Sprintf("FitnessKind(%d)", k)
+}
+
+func (k FitnessKind) MarshalText() ([]byte, error) {
+	if k > WEIGHTED {
+		return nil, fmt.Errorf("unknown fitness function %d", k)
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *FitnessKind) UnmarshalText(text []byte) error {
+	for _, kind := range []FitnessKind{LIFETIME, FOOD, OFFSPRING, DISTANCE, KILLS, WEIGHTED} {
+		if string(text) == kind.String() {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown fitness function %q, expected lifetime, food, offspring, distance, kills or weighted", text)
+}
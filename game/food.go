@@ -13,9 +13,10 @@ type Food struct {
 	y             float64
 	w             float64
 	h             float64
-	fp            int
-	maxFp         int
-	ticksToRegrow int
+	fp            float64
+	maxFp         float64
+	eatenThisTick float64
+	ticksToGrow   int
 	sprites       []*pixel.Sprite
 	currSpriteIdx int
 	mu            sync.Mutex
@@ -41,7 +42,7 @@ func InitFood(x, y float64) *Food {
 
 	sprites[0] = pixel.NewSprite(spritesheet, frames[0])
 	sprites[1] = pixel.NewSprite(spritesheet, frames[1])
-	return &Food{x: x, y: y, w: w, h: h, fp: 1, maxFp: 10, sprites: sprites}
+	return &Food{x: x, y: y, w: w, h: h, fp: 1, maxFp: Game.Plants.MaxFp, ticksToGrow: Game.Plants.GrowthPeriod, sprites: sprites}
 }
 
 func (f *Food) GetPos() (x, y float64) {
@@ -56,7 +57,13 @@ func (f *Food) GetCurrSprite() *pixel.Sprite {
 	return f.sprites[f.currSpriteIdx]
 }
 
-func (f *Food) Eeat() int {
+func (f *Food) GetSize() float64 {
+	return f.fp / f.maxFp
+}
+
+// Takes a bite of at most amount fp. A plant can only be eaten its yield
+// per tick, so animals crowding on the same cell have to share it.
+func (f *Food) Bite(amount float64) float64 {
 	if f == nil {
 		return 0
 	}
@@ -64,17 +71,17 @@ func (f *Food) Eeat() int {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	fpEaten := 0
-	if f.fp > 0 {
-		f.fp--
-		if f.fp == 0 {
-			f.currSpriteIdx = 1
-			f.ticksToRegrow = Game.TicksPerSecond * 30
-			FoodBlocksToGrow = append(FoodBlocksToGrow, f)
-		}
-		fpEaten = 1
+	fpEaten := min(amount, f.fp, Game.Plants.YieldPerTick-f.eatenThisTick)
+	if fpEaten <= 0 {
+		return 0
 	}
 
+	f.fp -= fpEaten
+	f.eatenThisTick += fpEaten
+	if f.fp <= 0 {
+		f.fp = 0
+		f.currSpriteIdx = 1
+	}
 
 	return fpEaten
 }
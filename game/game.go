@@ -1,43 +1,113 @@
 package game
 
 import (
+	"fmt"
 	"image"
+	"maps"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 	"os"
+	"slices"
+	"sync"
 
 	_ "image/png"
 
+	"example.com/artificial-life/neat"
 	"github.com/gopxl/pixel/v2"
 )
 
-type GameConfig struct {
-	TicksPerSecond int
-}
-
 var Game GameConfig
 var Animals []*Animal
 var newAnimals []*Animal
 var FoodBlocks = make(map[int]*Food)
-var FoodBlocksToGrow []*Food
-var Squares []pixel.Vec
+var Ticks int
+
+// Tells the checkpoints of a run apart from the ones of other runs. A
+// resumed run keeps its id.
+var RunId string
+
+// Every random decision in the world, including the ones taken by the
+// brains, comes from Rng so that a run can be reproduced from its seed.
+var rngSource = rand.NewPCG(rand.Uint64(), rand.Uint64())
+var Rng = rand.New(rngSource)
+
+func init() {
+	neat.SetRng(Rng)
+}
+
+func Seed(seed uint64) {
+	rngSource = rand.NewPCG(seed, seed)
+	Rng = rand.New(rngSource)
+	neat.SetRng(Rng)
+}
+
+// Not taken from Rng, which only the simulation may draw from.
+func newRunId() string {
+	return fmt.Sprintf("%08x", rand.Uint32())
+}
 
 func HashCoords(x, y float64) int {
 	return int(x)*100_000 + int(y)
 }
 
 func InitGameConfig() {
-	Game = GameConfig{TicksPerSecond: 60}
+	if err := InitWorld(DefaultConfig()); err != nil {
+		panic(err)
+	}
+}
+
+// Empties the world, keeping only its random number generator.
+func resetWorld() {
+	Animals = nil
+	newAnimals = nil
+	FoodBlocks = make(map[int]*Food)
+	Corpses = make(map[int]*Corpse)
+	Ticks = 0
+	RunId = newRunId()
+	nextAnimalId = 1
+	Lineage = NewLineageStore()
+	SpeciesList = nil
+	nextSpeciesId = 1
+	TotalBirths = 0
+	TotalDeaths = 0
+	Champions = &HallOfFame{}
+}
+
+func InitWorld(config GameConfig) error {
+	tiles := int(config.WorldSize / TILE_SIZE)
+	var terrain *Terrain
+	if config.TerrainFile != "" {
+		var err error
+		terrain, err = LoadTerrain(config.TerrainFile)
+		if err != nil {
+			return fmt.Errorf("terrain file: %w", err)
+		}
+		if terrain.width != tiles || terrain.height != tiles {
+			return fmt.Errorf("terrain file %s is %dx%d tiles, a world of size %g needs %dx%d",
+				config.TerrainFile, terrain.width, terrain.height, config.WorldSize, tiles, tiles)
+		}
+	}
 
-	for x := 0; x < 4096; x += 32 {
-		for y := 0; y < 4096; y += 32 {
-			if rand.Float64() < 0.05 {
-				FoodBlocks[HashCoords(float64(x), float64(y))] = InitFood(float64(x), float64(y))
+	resetWorld()
+	Game = config
+	neat.SetMutationRates(Game.Mutation)
+	Fitness = Game.Fitness.Func()
+	if terrain == nil {
+		terrain = GenerateTerrain(tiles, tiles)
+	}
+	TerrainMap = terrain
+	InitFertileZones(Game.WorldSize)
+
+	for x := 0; x < int(Game.WorldSize); x += 32 {
+		for y := 0; y < int(Game.WorldSize); y += 32 {
+			// Only where plants could grow later on, e.g. not in water.
+			if Rng.Float64() < Game.InitialPlantChance*FertilityAt(float64(x), float64(y)) {
+				spawnPlant(float64(x), float64(y))
 			}
-			if rand.Float64() < 0.005 {
-				r := rand.Float64()
+			if Rng.Float64() < Game.InitialAnimalChance && TerrainMap.IsPassable(float64(x), float64(y)) {
+				r := Rng.Float64()
 				var animalType AnimalType
-				if r < 0.0 {
+				if r < Game.HunterRatio {
 					animalType = HUNTER
 				} else {
 					animalType = PREY
@@ -46,9 +116,21 @@ func InitGameConfig() {
 			}
 		}
 	}
+	updateCrowding(false)
+	return nil
 }
 
+// Pictures are decoded once, every plant and animal sharing them.
+var pictures = make(map[string]pixel.Picture)
+var picturesMu sync.Mutex
+
 func LoadPicture(path string) (pixel.Picture, error) {
+	picturesMu.Lock()
+	defer picturesMu.Unlock()
+	if pic, ok := pictures[path]; ok {
+		return pic, nil
+	}
+
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -58,49 +140,86 @@ func LoadPicture(path string) (pixel.Picture, error) {
 	if err != nil {
 		return nil, err
 	}
-	return pixel.PictureDataFromImage(img), nil
+	pic := pixel.PictureDataFromImage(img)
+	pictures[path] = pic
+	return pic, nil
 }
 
 func PruneDeadAnimals() {
-	for k := 0; k < len(Animals); k++ {
-		if Animals[k].GetHP() <= 0 {
-			if len(Animals) < 5 {
-				newAnimal := Animals[k].Copy()
-				newAnimal.TurnDelta(rand.NormFloat64() * math.Pi)
-				newAnimal.hp = 20
-				newAnimal.ticksUntilHurt = 100
-				newAnimal.ticksToAppear = Game.TicksPerSecond + 1
-				newAnimal.fitness = 0
-				newAnimal.fitnessGoal = Animals[k].fitness
-				newAnimal.brain.MutateHighVariability()
-
-				newAnimals = append(newAnimals, newAnimal)
-			}
-			Animals = append(Animals[:k], Animals[k+1:]...)
+	// Removed first, so that the dead don't count as living while their
+	// type's population is checked.
+	var dead []*Animal
+	living := Animals[:0]
+	for _, a := range Animals {
+		if a.GetHP() <= 0 {
+			dead = append(dead, a)
+		} else {
+			living = append(living, a)
+		}
+	}
+	clear(Animals[len(living):])
+	Animals = living
+
+	for _, a := range dead {
+		Champions.consider(a)
+		if belowMinimum(a.animalType) {
+			recoverFrom(a)
+		}
+		if a.killedBy != 0 {
+			emit(Event{Type: DEATH, Animal: a.id, Cause: CAUSE_KILLED, Other: a.killedBy, X: a.x, Y: a.y})
+		} else {
+			emit(Event{Type: DEATH, Animal: a.id, Cause: CAUSE_STARVATION, X: a.x, Y: a.y})
 		}
+		Lineage.recordDeath(a)
+		a.leaveSpecies()
+		TotalDeaths++
+		LeaveCorpse(a)
 	}
 
-	for k := 0; k < len(newAnimals); k++ {
-		animal := newAnimals[k]
-		animal.ticksToAppear--
-		if animal.ticksToAppear <= 0 {
-			Animals = append(Animals, animal)
-			newAnimals = append(newAnimals[:k], newAnimals[k+1:]...)
+	waiting := newAnimals[:0]
+	for _, a := range newAnimals {
+		a.ticksToAppear--
+		if a.ticksToAppear <= 0 {
+			Animals = append(Animals, a)
+		} else {
+			waiting = append(waiting, a)
 		}
 	}
+	clear(newAnimals[len(waiting):])
+	newAnimals = waiting
 }
 
-func RegrowPlants() {
-	for i := 0; i < len(FoodBlocksToGrow); i++ {
-		v := FoodBlocksToGrow[i]
-		v.ticksToRegrow--
-		if v.ticksToRegrow <= 0 {
-			v.currSpriteIdx = 0
-			v.fp = 1
+func Tick() {
+	applyInterventions()
 
-			FoodBlocksToGrow = append(FoodBlocksToGrow[:i], FoodBlocksToGrow[i+1:]...)
-		}
+	wg := new(sync.WaitGroup)
+	for _, animal := range Animals {
+		wg.Add(1)
+		go animal.Think(wg)
+	}
+	wg.Wait()
+
+	for _, animal := range Animals {
+		animal.Update()
 	}
+
+	PruneDeadAnimals()
+	immigrate()
+	updateCrowding(true)
+	DecayCorpses()
+	GrowPlants()
+	Ticks++
+}
+
+// Map iteration order is random, anything which draws random numbers while
+// going through the food has to do it in a fixed order.
+func sortedFoodKeys() []int {
+	return slices.Sorted(maps.Keys(FoodBlocks))
+}
+
+// No animal is left, not even one waiting to be born.
+func IsExtinct() bool {
+	return len(Animals) == 0 && len(newAnimals) == 0
 }
 
 func IsCellFull(x, y float64) bool {
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"example.com/artificial-life/neat"
+)
+
+// Fittest animals ever seen in the world, by the world's fitness function,
+// fittest first.
+type HallOfFame struct {
+	Entries []HallOfFameEntry `json:"entries"`
+}
+
+type HallOfFameEntry struct {
+	AnimalId   int          `json:"animalId"`
+	Species    int          `json:"species"`
+	Generation int          `json:"generation"`
+	AnimalType AnimalType   `json:"animalType"`
+	Fitness    float64      `json:"fitness"`
+	Tick       int          `json:"tick"`
+	Brain      *neat.Genome `json:"brain"`
+}
+
+var Champions = &HallOfFame{}
+
+// Puts the animal in the hall of fame if it is fit enough, replacing its
+// previous entry if it already had one.
+func (h *HallOfFame) consider(a *Animal) {
+	size := Game.Recovery.HallOfFameSize
+	if i := slices.IndexFunc(h.Entries, func(e HallOfFameEntry) bool { return e.AnimalId == a.id }); i >= 0 {
+		h.Entries = slices.Delete(h.Entries, i, i+1)
+	} else if len(h.Entries) >= size && (size == 0 || a.fitness <= h.Entries[len(h.Entries)-1].Fitness) {
+		return
+	}
+
+	h.Entries = append(h.Entries, HallOfFameEntry{
+		AnimalId:   a.id,
+		Species:    a.species,
+		Generation: a.generation,
+		AnimalType: a.animalType,
+		Fitness:    a.fitness,
+		Tick:       Ticks,
+		Brain:      a.brain.Copy(),
+	})
+	slices.SortStableFunc(h.Entries, func(x, y HallOfFameEntry) int {
+		if x.Fitness != y.Fitness {
+			return cmp.Compare(y.Fitness, x.Fitness)
+		}
+		return x.AnimalId - y.AnimalId
+	})
+	if len(h.Entries) > size {
+		h.Entries = h.Entries[:size]
+	}
+}
+
+func (h *HallOfFame) Copy() *HallOfFame {
+	c := &HallOfFame{}
+	for _, e := range h.Entries {
+		e.Brain = e.Brain.Copy()
+		c.Entries = append(c.Entries, e)
+	}
+	return c
+}
+
+// The hall of fame as it would be if every living animal died now.
+func (h *HallOfFame) WithLiving() *HallOfFame {
+	c := h.Copy()
+	for _, a := range Animals {
+		c.consider(a)
+	}
+	return c
+}
+
+func (h *HallOfFame) WriteFile(path string) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Reads a hall of fame, or a single genome. A genome doesn't tell which
+// type of animal it belongs to, so it is entered once for each.
+func ReadHallOfFameFile(path string) (*HallOfFame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var h HallOfFame
+	if err := json.Unmarshal(data, &h); err == nil && h.Entries != nil {
+		return &h, nil
+	}
+	var genome neat.Genome
+	if err := json.Unmarshal(data, &genome); err != nil {
+		return nil, fmt.Errorf("%s: neither a hall of fame nor a genome: %w", path, err)
+	}
+	return &HallOfFame{Entries: []HallOfFameEntry{
+		{AnimalType: PREY, Brain: &genome},
+		{AnimalType: HUNTER, Brain: &genome},
+	}}, nil
+}
+
+// Brains fit for animals of the type seeing through that many rays.
+func (h *HallOfFame) candidates(animalType AnimalType, fovRays int) []*neat.Genome {
+	var candidates []*neat.Genome
+	for _, e := range h.Entries {
+		if e.AnimalType == animalType && e.Brain != nil && e.Brain.GetNumberOfInputs() == 2*fovRays+1 {
+			candidates = append(candidates, e.Brain)
+		}
+	}
+	return candidates
+}
+
+func (h *HallOfFame) pick(animalType AnimalType, fovRays int) *neat.Genome {
+	candidates := h.candidates(animalType, fovRays)
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[Rng.IntN(len(candidates))]
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A change to the world asked for from outside the simulation, e.g. through
+// the API. Interventions are queued and applied at the start of the next
+// tick, each one recorded as an event so that a replay applies it again.
+type Intervention struct {
+	Kind       string
+	X, Y       float64
+	AnimalType AnimalType
+}
+
+const (
+	SPAWN_ANIMAL = "spawnAnimal"
+	SPAWN_PLANT  = "spawnPlant"
+)
+
+var interventions []Intervention
+var interventionsMu sync.Mutex
+
+func Intervene(i Intervention) {
+	interventionsMu.Lock()
+	defer interventionsMu.Unlock()
+	interventions = append(interventions, i)
+}
+
+// Whether the intervention would succeed if the world stayed as it is
+// until the next tick.
+func (i Intervention) Check() error {
+	switch i.Kind {
+	case SPAWN_ANIMAL:
+		if !TerrainMap.IsPassable(i.X, i.Y) {
+			return fmt.Errorf("(%g, %g) is not passable", i.X, i.Y)
+		}
+	case SPAWN_PLANT:
+		x, y := snapToCell(i.X, i.Y)
+		if FoodBlocks[HashCoords(x, y)] != nil || !IsInsideWorld(x, y) || !TerrainMap.IsFertile(x, y) {
+			return fmt.Errorf("a plant can't grow at (%g, %g)", i.X, i.Y)
+		}
+	default:
+		return fmt.Errorf("unknown intervention %q", i.Kind)
+	}
+	return nil
+}
+
+func applyInterventions() {
+	interventionsMu.Lock()
+	pending := interventions
+	interventions = nil
+	interventionsMu.Unlock()
+
+	for _, i := range pending {
+		e := Event{Type: INTERVENTION, Cause: i.Kind, X: i.X, Y: i.Y}
+		if i.Kind == SPAWN_ANIMAL {
+			e.AnimalType = i.AnimalType.String()
+		}
+		emit(e)
+
+		// The world may have changed since the intervention was checked,
+		// in which case it does nothing, the same way in a replay.
+		switch i.Kind {
+		case SPAWN_ANIMAL:
+			spawnAnimal(i.X, i.Y, i.AnimalType)
+		case SPAWN_PLANT:
+			spawnPlant(i.X, i.Y)
+		}
+	}
+}
+
+func interventionOf(e Event) (Intervention, error) {
+	animalType, err := ParseAnimalType(e.AnimalType)
+	if err != nil {
+		return Intervention{}, err
+	}
+	return Intervention{Kind: e.Cause, X: e.X, Y: e.Y, AnimalType: animalType}, nil
+}
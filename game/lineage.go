@@ -0,0 +1,190 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type LineageRecord struct {
+	Id         int `json:"id"`
+	ParentId   int `json:"parentId"`
+	Generation int `json:"generation"`
+	BirthTick  int `json:"birthTick"`
+	DeathTick  int `json:"deathTick"`
+}
+
+func (r *LineageRecord) IsAlive() bool {
+	return r.DeathTick < 0
+}
+
+// Keeps track of every animal that ever lived and who its parent was.
+type LineageStore struct {
+	records  map[int]*LineageRecord
+	children map[int][]int
+}
+
+var Lineage = NewLineageStore()
+
+func NewLineageStore() *LineageStore {
+	return &LineageStore{records: make(map[int]*LineageRecord), children: make(map[int][]int)}
+}
+
+func (l *LineageStore) add(r LineageRecord) {
+	l.records[r.Id] = &r
+	l.children[r.ParentId] = append(l.children[r.ParentId], r.Id)
+}
+
+func (l *LineageStore) recordBirth(a *Animal) {
+	l.add(LineageRecord{
+		Id:         a.id,
+		ParentId:   a.parentId,
+		Generation: a.generation,
+		BirthTick:  a.birthTick,
+		DeathTick:  -1,
+	})
+}
+
+func (l *LineageStore) recordDeath(a *Animal) {
+	if r := l.records[a.id]; r != nil {
+		r.DeathTick = Ticks
+	}
+}
+
+func (l *LineageStore) Get(id int) (LineageRecord, bool) {
+	r := l.records[id]
+	if r == nil {
+		return LineageRecord{}, false
+	}
+	return *r, true
+}
+
+func (l *LineageStore) Records() []LineageRecord {
+	var records []LineageRecord
+	for _, id := range slices.Sorted(maps.Keys(l.records)) {
+		records = append(records, *l.records[id])
+	}
+	return records
+}
+
+// Records of the living animals and of their ancestors. Branches which
+// died out can't make a difference to the rest of a run, snapshots leave
+// them out.
+func (l *LineageStore) LivingRecords() []LineageRecord {
+	keep := make(map[int]bool)
+	for _, r := range l.records {
+		if !r.IsAlive() {
+			continue
+		}
+		for ; r != nil && !keep[r.Id]; r = l.records[r.ParentId] {
+			keep[r.Id] = true
+		}
+	}
+
+	var records []LineageRecord
+	for _, id := range slices.Sorted(maps.Keys(keep)) {
+		records = append(records, *l.records[id])
+	}
+	return records
+}
+
+// Parent first, up to the founder of the lineage.
+func (l *LineageStore) Ancestors(id int) []int {
+	var ancestors []int
+	for r := l.records[id]; r != nil && r.ParentId != 0; r = l.records[r.ParentId] {
+		ancestors = append(ancestors, r.ParentId)
+	}
+	return ancestors
+}
+
+func (l *LineageStore) Children(id int) []int {
+	return slices.Clone(l.children[id])
+}
+
+func (l *LineageStore) Descendants(id int) []int {
+	var descendants []int
+	pending := l.Children(id)
+	for len(pending) > 0 {
+		child := pending[0]
+		pending = pending[1:]
+		descendants = append(descendants, child)
+		pending = append(pending, l.children[child]...)
+	}
+	return descendants
+}
+
+// Most recent common ancestor of the given animals, an animal counting as
+// its own ancestor. Returns 0 when they don't share one.
+func (l *LineageStore) MostRecentCommonAncestor(ids ...int) int {
+	if len(ids) == 0 {
+		return 0
+	}
+
+	common := append([]int{ids[0]}, l.Ancestors(ids[0])...)
+	for _, id := range ids[1:] {
+		line := append([]int{id}, l.Ancestors(id)...)
+		common = slices.DeleteFunc(common, func(a int) bool {
+			return !slices.Contains(line, a)
+		})
+		if len(common) == 0 {
+			return 0
+		}
+	}
+	return common[0]
+}
+
+func (l *LineageStore) LivingMostRecentCommonAncestor() int {
+	var living []int
+	for _, r := range l.records {
+		if r.IsAlive() {
+			living = append(living, r.Id)
+		}
+	}
+	slices.Sort(living)
+	return l.MostRecentCommonAncestor(living...)
+}
+
+// Writes the whole lineage as a Newick tree, branch lengths being the
+// ticks between the births of a parent and its child.
+func (l *LineageStore) WriteNewick(w io.Writer) error {
+	var sb strings.Builder
+	roots := l.children[0]
+	if len(roots) > 1 {
+		sb.WriteString("(")
+	}
+	for i, root := range roots {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		l.writeNewickNode(&sb, root)
+	}
+	if len(roots) > 1 {
+		sb.WriteString(")")
+	}
+	sb.WriteString(";\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (l *LineageStore) writeNewickNode(sb *strings.Builder, id int) {
+	r := l.records[id]
+	if children := l.children[id]; len(children) > 0 {
+		sb.WriteString("(")
+		for i, child := range children {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			l.writeNewickNode(sb, child)
+		}
+		sb.WriteString(")")
+	}
+
+	length := 0
+	if parent := l.records[r.ParentId]; parent != nil {
+		length = r.BirthTick - parent.BirthTick
+	}
+	fmt.Fprintf(sb, "%d:%d", id, length)
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 1 and 2 are founders, 5 and 6 are the only ones alive.
+//
+//	1 ─┬─ 3 ── 5
+//	   └─ 4
+//	2 ─── 6
+func testLineage() *LineageStore {
+	l := NewLineageStore()
+	for _, r := range []LineageRecord{
+		{Id: 1, BirthTick: 0, DeathTick: 40},
+		{Id: 2, BirthTick: 0, DeathTick: 50},
+		{Id: 3, ParentId: 1, Generation: 1, BirthTick: 10, DeathTick: 45},
+		{Id: 4, ParentId: 1, Generation: 1, BirthTick: 15, DeathTick: 20},
+		{Id: 5, ParentId: 3, Generation: 2, BirthTick: 30, DeathTick: -1},
+		{Id: 6, ParentId: 2, Generation: 1, BirthTick: 25, DeathTick: -1},
+	} {
+		l.add(r)
+	}
+	return l
+}
+
+func TestMostRecentCommonAncestor(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+		want int
+	}{
+		{"none", nil, 0},
+		{"an animal is its own ancestor", []int{5}, 5},
+		{"siblings", []int{3, 4}, 1},
+		{"cousins", []int{5, 4}, 1},
+		{"parent and child", []int{5, 3}, 3},
+		{"different founders", []int{5, 6}, 0},
+		{"unknown animal", []int{5, 99}, 0},
+	}
+	l := testLineage()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.MostRecentCommonAncestor(tt.ids...); got != tt.want {
+				t.Errorf("MostRecentCommonAncestor(%v) = %d, want %d", tt.ids, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteNewick(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []LineageRecord
+		want    string
+	}{
+		{"empty", nil, ";\n"},
+		{"single founder", []LineageRecord{{Id: 1}}, "1:0;\n"},
+		{"branch lengths in ticks", []LineageRecord{
+			{Id: 1},
+			{Id: 2, ParentId: 1, BirthTick: 10},
+			{Id: 3, ParentId: 2, BirthTick: 35},
+		}, "((3:25)2:10)1:0;\n"},
+		{"founders are joined at the root", []LineageRecord{
+			{Id: 1},
+			{Id: 2},
+			{Id: 3, ParentId: 1, BirthTick: 5},
+			{Id: 4, ParentId: 1, BirthTick: 7},
+		}, "((3:5,4:7)1:0,2:0);\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLineageStore()
+			for _, r := range tt.records {
+				l.add(r)
+			}
+			var sb strings.Builder
+			if err := l.WriteNewick(&sb); err != nil {
+				t.Fatal(err)
+			}
+			if sb.String() != tt.want {
+				t.Errorf("WriteNewick() = %q, want %q", sb.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLivingRecords(t *testing.T) {
+	var got []int
+	for _, r := range testLineage().LivingRecords() {
+		got = append(got, r.Id)
+	}
+	// 4 died out.
+	if want := []int{1, 2, 3, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("LivingRecords() = %v, want %v", got, want)
+	}
+}
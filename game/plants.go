@@ -0,0 +1,115 @@
+package game
+
+import (
+	"math"
+)
+
+type FertileZone struct {
+	x         float64
+	y         float64
+	radius    float64
+	fertility float64
+}
+
+var FertileZones []FertileZone
+
+func InitFertileZones(worldSize float64) {
+	FertileZones = nil
+	for i := 0; i < Game.Plants.FertileZones; i++ {
+		FertileZones = append(FertileZones, FertileZone{
+			x:         Rng.Float64() * worldSize,
+			y:         Rng.Float64() * worldSize,
+			radius:    worldSize / 16 * (1 + Rng.Float64()),
+			fertility: 0.5 + Rng.Float64()/2,
+		})
+	}
+}
+
+// Fertility at a point, between the base fertility away from every zone
+// and 1 at the center of the most fertile ones.
+func FertilityAt(x, y float64) float64 {
+	fertility := Game.Plants.BaseFertility
+	for _, zone := range FertileZones {
+		d := math.Hypot(x-zone.x, y-zone.y)
+		if d < zone.radius {
+			fertility = max(fertility, zone.fertility*(1-d/zone.radius))
+		}
+	}
+	return fertility
+}
+
+// Plants grow faster in summer and barely grow at all in winter.
+func SeasonFactor() float64 {
+	return 1 + 0.75*math.Sin(2*math.Pi*float64(Ticks)/float64(Game.Plants.SeasonLength))
+}
+
+func spawnPlant(x, y float64) *Food {
+	x, y = snapToCell(x, y)
+	key := HashCoords(x, y)
+	if FoodBlocks[key] != nil || !IsInsideWorld(x, y) || !TerrainMap.IsFertile(x, y) {
+		return nil
+	}
+
+	food := InitFood(x, y)
+	FoodBlocks[key] = food
+	return food
+}
+
+func spawnPlantGroups() {
+	for _, zone := range FertileZones {
+		if Rng.Float64() > zone.fertility*SeasonFactor() {
+			continue
+		}
+		for i := 0; i < Game.Plants.SpawnGroup; i++ {
+			r := zone.radius * math.Sqrt(Rng.Float64())
+			theta := Rng.Float64() * 2 * math.Pi
+			spawnPlant(zone.x+r*math.Cos(theta), zone.y+r*math.Sin(theta))
+		}
+	}
+}
+
+func (f *Food) grow() {
+	f.eatenThisTick = 0
+	f.ticksToGrow--
+	if f.ticksToGrow > 0 {
+		return
+	}
+
+	f.ticksToGrow = int(float64(Game.Plants.GrowthPeriod) / max(0.1, SeasonFactor()*FertilityAt(f.x, f.y)))
+	if f.fp < f.maxFp {
+		f.fp = min(f.fp+1, f.maxFp)
+		f.currSpriteIdx = 0
+	}
+}
+
+func (f *Food) seed() {
+	if f.fp < f.maxFp || Rng.Float64() > Game.Plants.SeedChance*SeasonFactor() {
+		return
+	}
+
+	dx := float64(Rng.IntN(3)-1) * 16
+	dy := float64(Rng.IntN(3)-1) * 16
+	if Rng.Float64() < FertilityAt(f.x+dx, f.y+dy) {
+		spawnPlant(f.x+dx, f.y+dy)
+	}
+}
+
+func GrowPlants() {
+	var seeding []*Food
+	for _, key := range sortedFoodKeys() {
+		food := FoodBlocks[key]
+		if food.fp <= 0 && Rng.Float64() < Game.Plants.WitherChance*(1-FertilityAt(food.x, food.y)) {
+			delete(FoodBlocks, key)
+			continue
+		}
+		food.grow()
+		seeding = append(seeding, food)
+	}
+	for _, food := range seeding {
+		food.seed()
+	}
+
+	if Ticks%Game.Plants.SpawnPeriod == 0 {
+		spawnPlantGroups()
+	}
+}
@@ -0,0 +1,201 @@
+package game
+
+import "example.com/artificial-life/neat"
+
+type PopulationControl struct {
+	// While fewer than Min are alive or about to be born, dying animals are
+	// replaced according to the recovery policy. At Max, animals don't
+	// reproduce. 0 means no maximum.
+	Min int `json:"min"`
+	Max int `json:"max"`
+	// Ticks between two immigrants arriving at the edge of the map, 0
+	// disables immigration. Their brains come from the given source, a
+	// mutant being the copy of a living animal of the same type.
+	ImmigrationPeriod int            `json:"immigrationPeriod"`
+	Immigrants        RecoveryPolicy `json:"immigrants"`
+	// Above it, animals go hungry faster the more crowded the world is. 0
+	// disables it.
+	CarryingCapacity int `json:"carryingCapacity"`
+}
+
+type PopulationControls struct {
+	Prey    PopulationControl `json:"prey"`
+	Hunters PopulationControl `json:"hunters"`
+}
+
+func (p PopulationControls) For(animalType AnimalType) PopulationControl {
+	if animalType == HUNTER {
+		return p.Hunters
+	}
+	return p.Prey
+}
+
+const (
+	CAUSE_BELOW_MINIMUM = "belowMinimum"
+	CAUSE_AT_MAXIMUM    = "atMaximum"
+	CAUSE_IMMIGRATION   = "immigration"
+	CAUSE_OVER_CAPACITY = "overCapacity"
+	CAUSE_BACK_CAPACITY = "backUnderCapacity"
+	// The source of a replacement or an immigrant had no suitable brain, a
+	// fallback was used instead.
+	CAUSE_NO_GENOME = "noGenome"
+)
+
+// How far above its carrying capacity every animal type is, as a fraction
+// of the capacity. Updated at the end of every tick.
+var crowding [HUNTER + 1]float64
+
+// Animals of the type in the world, not counting the ones waiting to be
+// born.
+func CountAnimals(animalType AnimalType) int {
+	n := 0
+	for _, a := range Animals {
+		if a.animalType == animalType {
+			n++
+		}
+	}
+	return n
+}
+
+// Born animals count towards the limits even before they appear.
+func countWithUnborn(animalType AnimalType) int {
+	n := CountAnimals(animalType)
+	for _, a := range newAnimals {
+		if a.animalType == animalType {
+			n++
+		}
+	}
+	return n
+}
+
+func atMaximum(animalType AnimalType) bool {
+	limit := Game.Population.For(animalType).Max
+	return limit > 0 && countWithUnborn(animalType) >= limit
+}
+
+func belowMinimum(animalType AnimalType) bool {
+	return countWithUnborn(animalType) < Game.Population.For(animalType).Min
+}
+
+// Extra hunger of crowded animals: one more tick of it, with a chance
+// growing with how far above capacity the population is.
+func (a *Animal) crowdingHunger() int {
+	excess := crowding[a.animalType]
+	if excess <= 0 {
+		return 0
+	}
+	if Rng.Float64() < excess {
+		return 1
+	}
+	return 0
+}
+
+func updateCrowding(report bool) {
+	for _, animalType := range []AnimalType{PREY, HUNTER} {
+		capacity := Game.Population.For(animalType).CarryingCapacity
+		excess := 0.0
+		count := CountAnimals(animalType)
+		if capacity > 0 && count > capacity {
+			excess = float64(count-capacity) / float64(capacity)
+		}
+
+		if report && (excess > 0) != (crowding[animalType] > 0) {
+			cause := CAUSE_OVER_CAPACITY
+			if excess == 0 {
+				cause = CAUSE_BACK_CAPACITY
+			}
+			emit(Event{Type: POPULATION, Cause: cause, AnimalType: animalType.String(), Count: count})
+		}
+		crowding[animalType] = excess
+	}
+}
+
+// Brain of an animal joining the world from the given source, nil after
+// reporting it if the source has none suitable.
+func sourceBrain(source RecoveryPolicy, animalType AnimalType, fovRays int) *neat.Genome {
+	var brain *neat.Genome
+	switch source {
+	case MUTANT:
+		var candidates []*Animal
+		for _, a := range Animals {
+			if a.animalType == animalType && a.fovRays == fovRays {
+				candidates = append(candidates, a)
+			}
+		}
+		if len(candidates) > 0 {
+			brain = candidates[Rng.IntN(len(candidates))].brain
+		}
+	case HALL_OF_FAME:
+		brain = Champions.pick(animalType, fovRays)
+	case FILE:
+		brain = recoveryGenomes.pick(animalType, fovRays)
+	case RANDOM:
+		brain = neat.CreateGenome(0, 2*fovRays+1, 3)
+		brain.InitializeFromInitialConfig()
+		return brain
+	}
+	if brain == nil {
+		emit(Event{Type: POPULATION, Cause: CAUSE_NO_GENOME, AnimalType: animalType.String()})
+		return nil
+	}
+
+	brain = brain.Copy()
+	brain.Mutate()
+	return brain
+}
+
+// A random passable position along the edge of the map.
+func edgePosition() (float64, float64, bool) {
+	for range 100 {
+		along := Rng.Float64() * (Game.WorldSize - TILE_SIZE)
+		far := Game.WorldSize - TILE_SIZE
+		var x, y float64
+		switch Rng.IntN(4) {
+		case 0:
+			x, y = along, 0
+		case 1:
+			x, y = along, far
+		case 2:
+			x, y = 0, along
+		default:
+			x, y = far, along
+		}
+		if TerrainMap.IsPassable(x, y) {
+			return x, y, true
+		}
+	}
+	return 0, 0, false
+}
+
+func immigrate() {
+	for _, animalType := range []AnimalType{PREY, HUNTER} {
+		control := Game.Population.For(animalType)
+		if control.ImmigrationPeriod <= 0 || Ticks%control.ImmigrationPeriod != 0 || atMaximum(animalType) {
+			continue
+		}
+		x, y, ok := edgePosition()
+		if !ok {
+			continue
+		}
+
+		brain := sourceBrain(control.Immigrants, animalType, Game.Animals.FovRays)
+		if brain == nil {
+			brain = sourceBrain(RANDOM, animalType, Game.Animals.FovRays)
+		}
+		a := initAnimal(x, y, animalType, brain)
+		Animals = append(Animals, a)
+		TotalBirths++
+		emit(Event{Type: BIRTH, Animal: a.id, X: x, Y: y})
+		emit(Event{Type: POPULATION, Cause: CAUSE_IMMIGRATION, Animal: a.id, AnimalType: animalType.String(),
+			Count: CountAnimals(animalType), X: x, Y: y})
+	}
+}
+
+func (c PopulationControl) validate(name string, check func(bool, string, ...any)) {
+	check(c.Min >= 0, "%s.min can't be negative, got %d", name, c.Min)
+	check(c.Max >= 0, "%s.max can't be negative, got %d", name, c.Max)
+	check(c.Max == 0 || c.Max >= c.Min, "%s.max must be at least %s.min, got %d", name, name, c.Max)
+	check(c.ImmigrationPeriod >= 0, "%s.immigrationPeriod can't be negative, got %d", name, c.ImmigrationPeriod)
+	check(c.Immigrants <= RANDOM, "unknown %s.immigrants %d", name, c.Immigrants)
+	check(c.CarryingCapacity >= 0, "%s.carryingCapacity can't be negative, got %d", name, c.CarryingCapacity)
+}
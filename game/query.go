@@ -0,0 +1,104 @@
+package game
+
+import (
+	"fmt"
+
+	"example.com/artificial-life/neat"
+)
+
+type AnimalInfo struct {
+	Id              int          `json:"id"`
+	ParentId        int          `json:"parentId"`
+	Generation      int          `json:"generation"`
+	Species         int          `json:"species"`
+	Type            string       `json:"type"`
+	X               float64      `json:"x"`
+	Y               float64      `json:"y"`
+	Heading         float64      `json:"heading"`
+	Hp              int          `json:"hp"`
+	TicksUntilHurt  int          `json:"ticksUntilHurt"`
+	Age             int          `json:"age"`
+	Fitness         float64      `json:"fitness"`
+	FitnessGoal     float64      `json:"fitnessGoal"`
+	FoodEaten       float64      `json:"foodEaten"`
+	Offspring       int          `json:"offspring"`
+	Distance        float64      `json:"distance"`
+	Kills           int          `json:"kills"`
+	PlantEfficiency float64      `json:"plantEfficiency"`
+	MeatEfficiency  float64      `json:"meatEfficiency"`
+	BiteRate        float64      `json:"biteRate"`
+	Eating          bool         `json:"eating"`
+	Brain           *neat.Genome `json:"brain,omitempty"`
+}
+
+func (t AnimalType) String() string {
+	if t == HUNTER {
+		return "hunter"
+	}
+	return "prey"
+}
+
+func ParseAnimalType(s string) (AnimalType, error) {
+	switch s {
+	case "prey", "":
+		return PREY, nil
+	case "hunter":
+		return HUNTER, nil
+	}
+	return PREY, fmt.Errorf("unknown animal type %q", s)
+}
+
+func (a *Animal) Info(withBrain bool) AnimalInfo {
+	info := AnimalInfo{
+		Id:              a.id,
+		ParentId:        a.parentId,
+		Generation:      a.generation,
+		Species:         a.species,
+		Type:            a.animalType.String(),
+		X:               a.x,
+		Y:               a.y,
+		Heading:         a.dirTheta,
+		Hp:              a.hp,
+		TicksUntilHurt:  a.ticksUntilHurt,
+		Age:             a.GetAge(),
+		Fitness:         a.fitness,
+		FitnessGoal:     a.fitnessGoal,
+		FoodEaten:       a.foodEaten,
+		Offspring:       a.offspring,
+		Distance:        a.distance,
+		Kills:           a.kills,
+		PlantEfficiency: a.diet.plantEfficiency,
+		MeatEfficiency:  a.diet.meatEfficiency,
+		BiteRate:        a.biteRate,
+		Eating:          a.eating,
+	}
+	if withBrain {
+		info.Brain = a.brain.Copy()
+	}
+	return info
+}
+
+func FindAnimal(id int) *Animal {
+	for _, a := range Animals {
+		if a.id == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func spawnAnimal(x, y float64, animalType AnimalType) (*Animal, error) {
+	if !TerrainMap.IsPassable(x, y) {
+		return nil, fmt.Errorf("(%g, %g) is not passable", x, y)
+	}
+
+	x, y = WrapPos(x, y)
+	a := InitAnimal(x, y, animalType)
+	Animals = append(Animals, a)
+	emit(Event{Type: BIRTH, Animal: a.id, X: x, Y: y})
+	return a, nil
+}
+
+func (a *Animal) GetType() AnimalType {
+	return a.animalType
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"fmt"
+
+	"example.com/artificial-life/neat"
+)
+
+// What dying animals leave behind while the population of their type is
+// below its minimum, and where immigrants come from.
+type RecoveryPolicy uint8
+
+const (
+	// A highly mutated copy of themselves, or for immigrants a mutated copy
+	// of a living animal.
+	MUTANT RecoveryPolicy = iota
+	// A mutated copy of a champion from the hall of fame.
+	HALL_OF_FAME
+	// A mutated copy of a genome read from RecoveryConfig.File.
+	FILE
+	// An animal with a fresh random brain.
+	RANDOM
+)
+
+type RecoveryConfig struct {
+	Policy RecoveryPolicy `json:"policy"`
+	// A hall of fame or a genome, for the file policy.
+	File           string `json:"file"`
+	HallOfFameSize int    `json:"hallOfFameSize"`
+}
+
+// Genomes the file policy picks from, read by LoadRecoveryFile.
+var recoveryGenomes = &HallOfFame{}
+
+func LoadRecoveryFile() error {
+	h, err := readRecoveryFile(Game)
+	if err != nil {
+		return err
+	}
+	recoveryGenomes = h
+	return nil
+}
+
+func readRecoveryFile(config GameConfig) (*HallOfFame, error) {
+	if config.Recovery.Policy != FILE && config.Population.Prey.Immigrants != FILE && config.Population.Hunters.Immigrants != FILE {
+		return &HallOfFame{}, nil
+	}
+	h, err := ReadHallOfFameFile(config.Recovery.File)
+	if err != nil {
+		return nil, fmt.Errorf("recovery file: %w", err)
+	}
+
+	// The file never changes, if it has nothing for a type which needs it
+	// it never will.
+	for _, animalType := range []AnimalType{PREY, HUNTER} {
+		control := config.Population.For(animalType)
+		recovers := config.Recovery.Policy == FILE && control.Min > 0
+		immigrates := control.Immigrants == FILE && control.ImmigrationPeriod > 0
+		if (recovers || immigrates) && len(h.candidates(animalType, config.Animals.FovRays)) == 0 {
+			return nil, fmt.Errorf("recovery file %s has no %s brain with %d inputs",
+				config.Recovery.File, animalType, 2*config.Animals.FovRays+1)
+		}
+	}
+	return h, nil
+}
+
+// Replaces a dying animal according to the recovery policy, falling back
+// to a mutant of itself when there is no suitable genome to pick from.
+func recoverFrom(dead *Animal) {
+	var brain *neat.Genome
+	if Game.Recovery.Policy != MUTANT {
+		brain = sourceBrain(Game.Recovery.Policy, dead.animalType, dead.fovRays)
+	}
+	var a *Animal
+	if brain == nil {
+		a = dead.spawnChild(true)
+	} else {
+		a = initAnimal(dead.x, dead.y, dead.animalType, brain)
+		a.ticksToAppear = Game.TicksPerSecond + 1
+		newAnimals = append(newAnimals, a)
+		TotalBirths++
+		emit(Event{Type: BIRTH, Animal: a.id, X: a.x, Y: a.y})
+	}
+	emit(Event{Type: POPULATION, Cause: CAUSE_BELOW_MINIMUM, Animal: a.id, Other: dead.id,
+		AnimalType: dead.animalType.String(), Count: CountAnimals(dead.animalType), X: a.x, Y: a.y})
+}
+
+func (p RecoveryPolicy) String() string {
+	switch p {
+	case MUTANT:
+		return "mutant"
+	case HALL_OF_FAME:
+		return "hallOfFame"
+	case FILE:
+		return "file"
+	case RANDOM:
+		return "random"
+	}
+	return fmt.Sprintf("RecoveryPolicy(%d)", p)
+}
+
+func (p RecoveryPolicy) MarshalText() ([]byte, error) {
+	if p > RANDOM {
+		return nil, fmt.Errorf("unknown recovery policy %d", p)
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *RecoveryPolicy) UnmarshalText(text []byte) error {
+	for _, policy := range []RecoveryPolicy{MUTANT, HALL_OF_FAME, FILE, RANDOM} {
+		if string(text) == policy.String() {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown recovery policy %q, expected mutant, hallOfFame, file or random", text)
+}
@@ -0,0 +1,172 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Rebuilds the world at any tick of a recorded run. Since a run is
+// deterministic, going to a tick means restoring the latest checkpoint
+// before it and simulating forward, while checking that the simulation
+// emits exactly the events found in the log.
+type Replay struct {
+	snapshots []*Snapshot
+	events    map[int][]Event
+	// Where the run was resumed with new parameters or a new seed.
+	resumes  map[int]Event
+	lastTick int
+}
+
+// The checkpoints have to belong to the run recorded in the log, and the
+// earliest of them has to come before the log starts.
+func NewReplay(checkpointDir, logPath string) (*Replay, error) {
+	events, err := ReadEventLog(logPath)
+	if err != nil && len(events) == 0 {
+		return nil, err
+	}
+
+	r := &Replay{events: make(map[int][]Event), resumes: make(map[int]Event)}
+	run := ""
+	firstTick := -1
+	for _, e := range events {
+		if firstTick < 0 {
+			firstTick = e.Tick
+		}
+		if e.Type == RUN {
+			if run != "" && e.Run != run {
+				return nil, fmt.Errorf("%s mixes the events of runs %s and %s", logPath, run, e.Run)
+			}
+			run = e.Run
+			if e.Config != nil || e.Seed != 0 {
+				r.resumes[e.Tick] = e
+			}
+			continue
+		}
+		r.events[e.Tick] = append(r.events[e.Tick], e)
+		r.lastTick = max(r.lastTick, e.Tick+1)
+	}
+	if run == "" {
+		return nil, fmt.Errorf("%s doesn't tell which run it recorded", logPath)
+	}
+
+	paths, err := ListCheckpoints(checkpointDir, run)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		s, err := readCheckpoint(path)
+		if err != nil {
+			return nil, err
+		}
+		if s.Run != run {
+			return nil, fmt.Errorf("%s belongs to run %s, not %s", path, s.Run, run)
+		}
+		r.snapshots = append(r.snapshots, s)
+	}
+	if len(r.snapshots) == 0 {
+		return nil, fmt.Errorf("no checkpoints of run %s in %s", run, checkpointDir)
+	}
+	slices.SortFunc(r.snapshots, func(a, b *Snapshot) int { return a.Ticks - b.Ticks })
+	if first := r.snapshots[0].Ticks; first > firstTick {
+		return nil, fmt.Errorf("the log starts at tick %d but the first checkpoint of run %s is at tick %d", firstTick, run, first)
+	}
+	r.lastTick = max(r.lastTick, r.snapshots[len(r.snapshots)-1].Ticks)
+
+	if err := RestoreSnapshot(r.snapshots[0]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Replay) FirstTick() int {
+	return r.snapshots[0].Ticks
+}
+
+// Last tick known to the replay, either from a checkpoint or the event log.
+func (r *Replay) LastTick() int {
+	return r.lastTick
+}
+
+func (r *Replay) EventsAt(tick int) []Event {
+	return r.events[tick]
+}
+
+func (r *Replay) Step() error {
+	var emitted []Event
+	sink := EventSink
+	EventSink = func(e Event) { emitted = append(emitted, e) }
+	defer func() { EventSink = sink }()
+
+	tick := Ticks
+	if e, ok := r.resumes[tick]; ok {
+		if e.Config != nil {
+			if err := Reconfigure(*e.Config); err != nil {
+				return err
+			}
+		}
+		if e.Seed != 0 {
+			Seed(e.Seed)
+		}
+	}
+
+	// Only the interventions of the recorded run happen in its replay.
+	var recorded []Intervention
+	for _, e := range r.events[tick] {
+		if e.Type != INTERVENTION {
+			continue
+		}
+		i, err := interventionOf(e)
+		if err != nil {
+			return err
+		}
+		recorded = append(recorded, i)
+	}
+	interventionsMu.Lock()
+	interventions = recorded
+	interventionsMu.Unlock()
+	Tick()
+
+	if tick >= r.lastTick {
+		return nil
+	}
+	expected, err := json.Marshal(r.events[tick])
+	if err != nil {
+		return err
+	}
+	got, err := json.Marshal(emitted)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expected, got) {
+		return fmt.Errorf("replay diverged from the log at tick %d", tick)
+	}
+	return nil
+}
+
+func (r *Replay) Seek(tick int) error {
+	tick = max(r.FirstTick(), min(tick, r.LastTick()))
+
+	if tick < Ticks || r.nearestSnapshot(tick).Ticks > Ticks {
+		if err := RestoreSnapshot(r.nearestSnapshot(tick)); err != nil {
+			return err
+		}
+	}
+	for Ticks < tick {
+		if err := r.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Replay) nearestSnapshot(tick int) *Snapshot {
+	best := r.snapshots[0]
+	for _, s := range r.snapshots {
+		if s.Ticks <= tick {
+			best = s
+		}
+	}
+	return best
+}
@@ -0,0 +1,457 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"slices"
+
+	"example.com/artificial-life/neat"
+)
+
+const SNAPSHOT_VERSION = 11
+
+// Oldest version of a snapshot which can still be migrated to the current
+// one.
+const OLDEST_SNAPSHOT_VERSION = 8
+
+type animalSnapshot struct {
+	Id              int          `json:"id"`
+	ParentId        int          `json:"parentId"`
+	Generation      int          `json:"generation"`
+	BirthTick       int          `json:"birthTick"`
+	Species         int          `json:"species"`
+	X               float64      `json:"x"`
+	Y               float64      `json:"y"`
+	W               float64      `json:"w"`
+	H               float64      `json:"h"`
+	DirTheta        float64      `json:"dirTheta"`
+	Speed           float64      `json:"speed"`
+	LastDx          float64      `json:"lastDx"`
+	LastDy          float64      `json:"lastDy"`
+	TurningState    TurningState `json:"turningState"`
+	TurningRate     float64      `json:"turningRate"`
+	TicksUntilHurt  int          `json:"ticksUntilHurt"`
+	FovRays         int          `json:"fovRays"`
+	Fov             float64      `json:"fov"`
+	ViewingDistance int          `json:"viewingDistance"`
+	Hp              int          `json:"hp"`
+	AnimalType      AnimalType   `json:"animalType"`
+	PlantEfficiency float64      `json:"plantEfficiency"`
+	MeatEfficiency  float64      `json:"meatEfficiency"`
+	BiteRate        float64      `json:"biteRate"`
+	HpDigested      float64      `json:"hpDigested"`
+	Eating          bool         `json:"eating"`
+	Meal            FoodType     `json:"meal"`
+	KilledBy        int          `json:"killedBy"`
+	TicksToAppear   int          `json:"ticksToAppear"`
+	FitnessGoal     float64      `json:"fitnessGoal"`
+	Fitness         float64      `json:"fitness"`
+	Lifetime        int          `json:"lifetime"`
+	FoodEaten       float64      `json:"foodEaten"`
+	Offspring       int          `json:"offspring"`
+	Distance        float64      `json:"distance"`
+	Kills           int          `json:"kills"`
+	ReproCoolDown   int          `json:"reproCoolDown"`
+	Brain           *neat.Genome `json:"brain"`
+}
+
+type foodSnapshot struct {
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Fp            float64 `json:"fp"`
+	MaxFp         float64 `json:"maxFp"`
+	EatenThisTick float64 `json:"eatenThisTick"`
+	TicksToGrow   int     `json:"ticksToGrow"`
+}
+
+type corpseSnapshot struct {
+	X            float64    `json:"x"`
+	Y            float64    `json:"y"`
+	W            float64    `json:"w"`
+	H            float64    `json:"h"`
+	Meat         int        `json:"meat"`
+	MaxMeat      int        `json:"maxMeat"`
+	TicksToDecay int        `json:"ticksToDecay"`
+	AnimalType   AnimalType `json:"animalType"`
+}
+
+type zoneSnapshot struct {
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Radius    float64 `json:"radius"`
+	Fertility float64 `json:"fertility"`
+}
+
+type speciesSnapshot struct {
+	Id             int          `json:"id"`
+	Representative *neat.Genome `json:"representative"`
+	Members        int          `json:"members"`
+}
+
+type terrainSnapshot struct {
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Tiles  []TileType `json:"tiles"`
+}
+
+type Snapshot struct {
+	Version       int               `json:"version"`
+	Run           string            `json:"run"`
+	Config        GameConfig        `json:"config"`
+	Ticks         int               `json:"ticks"`
+	NextAnimalId  int               `json:"nextAnimalId"`
+	TotalBirths   int               `json:"totalBirths"`
+	TotalDeaths   int               `json:"totalDeaths"`
+	Rng           []byte            `json:"rng"`
+	Terrain       terrainSnapshot   `json:"terrain"`
+	FertileZones  []zoneSnapshot    `json:"fertileZones"`
+	Animals       []animalSnapshot  `json:"animals"`
+	NewAnimals    []animalSnapshot  `json:"newAnimals"`
+	Food          []foodSnapshot    `json:"food"`
+	Corpses       []corpseSnapshot  `json:"corpses"`
+	Lineage       []LineageRecord   `json:"lineage"`
+	Species       []speciesSnapshot `json:"species"`
+	NextSpeciesId int               `json:"nextSpeciesId"`
+	HallOfFame    *HallOfFame       `json:"hallOfFame"`
+}
+
+func snapshotAnimal(a *Animal) animalSnapshot {
+	return animalSnapshot{
+		Id:              a.id,
+		ParentId:        a.parentId,
+		Generation:      a.generation,
+		BirthTick:       a.birthTick,
+		Species:         a.species,
+		X:               a.x,
+		Y:               a.y,
+		W:               a.w,
+		H:               a.h,
+		DirTheta:        a.dirTheta,
+		Speed:           a.speed,
+		LastDx:          a.lastDx,
+		LastDy:          a.lastDy,
+		TurningState:    a.turningState,
+		TurningRate:     a.turningRate,
+		TicksUntilHurt:  a.ticksUntilHurt,
+		FovRays:         a.fovRays,
+		Fov:             a.fov,
+		ViewingDistance: a.viewingDistance,
+		Hp:              a.hp,
+		AnimalType:      a.animalType,
+		PlantEfficiency: a.diet.plantEfficiency,
+		MeatEfficiency:  a.diet.meatEfficiency,
+		BiteRate:        a.biteRate,
+		HpDigested:      a.hpDigested,
+		Eating:          a.eating,
+		Meal:            a.meal,
+		KilledBy:        a.killedBy,
+		TicksToAppear:   a.ticksToAppear,
+		FitnessGoal:     a.fitnessGoal,
+		Fitness:         a.fitness,
+		Lifetime:        a.lifetime,
+		FoodEaten:       a.foodEaten,
+		Offspring:       a.offspring,
+		Distance:        a.distance,
+		Kills:           a.kills,
+		ReproCoolDown:   a.reproCoolDown,
+		Brain:           a.brain.Copy(),
+	}
+}
+
+func restoreAnimal(s animalSnapshot) *Animal {
+	return &Animal{
+		id:              s.Id,
+		parentId:        s.ParentId,
+		generation:      s.Generation,
+		birthTick:       s.BirthTick,
+		species:         s.Species,
+		x:               s.X,
+		y:               s.Y,
+		w:               s.W,
+		h:               s.H,
+		dirTheta:        s.DirTheta,
+		speed:           s.Speed,
+		lastDx:          s.LastDx,
+		lastDy:          s.LastDy,
+		turningState:    s.TurningState,
+		turningRate:     s.TurningRate,
+		ticksUntilHurt:  s.TicksUntilHurt,
+		fovRays:         s.FovRays,
+		fov:             s.Fov,
+		viewingDistance: s.ViewingDistance,
+		hp:              s.Hp,
+		animalType:      s.AnimalType,
+		diet:            Diet{plantEfficiency: s.PlantEfficiency, meatEfficiency: s.MeatEfficiency},
+		biteRate:        s.BiteRate,
+		hpDigested:      s.HpDigested,
+		eating:          s.Eating,
+		meal:            s.Meal,
+		killedBy:        s.KilledBy,
+		ticksToAppear:   s.TicksToAppear,
+		fitnessGoal:     s.FitnessGoal,
+		fitness:         s.Fitness,
+		lifetime:        s.Lifetime,
+		foodEaten:       s.FoodEaten,
+		offspring:       s.Offspring,
+		distance:        s.Distance,
+		kills:           s.Kills,
+		reproCoolDown:   s.ReproCoolDown,
+		brain:           s.Brain.Copy(),
+		sprite:          loadAnimalSprite(s.AnimalType),
+	}
+}
+
+func TakeSnapshot() (*Snapshot, error) {
+	rngState, err := rngSource.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		Version:      SNAPSHOT_VERSION,
+		Run:          RunId,
+		Config:       Game,
+		Ticks:        Ticks,
+		NextAnimalId: nextAnimalId,
+		TotalBirths:  TotalBirths,
+		TotalDeaths:  TotalDeaths,
+		Rng:          rngState,
+		Terrain:      terrainSnapshot{Width: TerrainMap.width, Height: TerrainMap.height, Tiles: TerrainMap.tiles},
+	}
+	for _, zone := range FertileZones {
+		s.FertileZones = append(s.FertileZones, zoneSnapshot{X: zone.x, Y: zone.y, Radius: zone.radius, Fertility: zone.fertility})
+	}
+	for _, animal := range Animals {
+		s.Animals = append(s.Animals, snapshotAnimal(animal))
+	}
+	for _, animal := range newAnimals {
+		s.NewAnimals = append(s.NewAnimals, snapshotAnimal(animal))
+	}
+	for _, key := range sortedFoodKeys() {
+		f := FoodBlocks[key]
+		s.Food = append(s.Food, foodSnapshot{
+			X:             f.x,
+			Y:             f.y,
+			Fp:            f.fp,
+			MaxFp:         f.maxFp,
+			EatenThisTick: f.eatenThisTick,
+			TicksToGrow:   f.ticksToGrow,
+		})
+	}
+	for _, key := range slices.Sorted(maps.Keys(Corpses)) {
+		c := Corpses[key]
+		s.Corpses = append(s.Corpses, corpseSnapshot{
+			X:            c.x,
+			Y:            c.y,
+			W:            c.w,
+			H:            c.h,
+			Meat:         c.meat,
+			MaxMeat:      c.maxMeat,
+			TicksToDecay: c.ticksToDecay,
+			AnimalType:   c.animalType,
+		})
+	}
+	s.Lineage = Lineage.LivingRecords()
+	s.HallOfFame = Champions.Copy()
+	s.NextSpeciesId = nextSpeciesId
+	for _, species := range SpeciesList {
+		s.Species = append(s.Species, speciesSnapshot{
+			Id:             species.id,
+			Representative: species.representative.Copy(),
+			Members:        species.members,
+		})
+	}
+
+	return s, nil
+}
+
+// Replaces the whole world with the one stored in the snapshot. The world
+// is left untouched if the snapshot can't be restored.
+func RestoreSnapshot(s *Snapshot) error {
+	if s.Version != SNAPSHOT_VERSION {
+		return fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, SNAPSHOT_VERSION)
+	}
+	if len(s.Terrain.Tiles) != s.Terrain.Width*s.Terrain.Height {
+		return fmt.Errorf("terrain has %d tiles, expected %dx%d", len(s.Terrain.Tiles), s.Terrain.Width, s.Terrain.Height)
+	}
+	if err := s.Config.Validate(); err != nil {
+		return err
+	}
+	source := &rand.PCG{}
+	if err := source.UnmarshalBinary(s.Rng); err != nil {
+		return err
+	}
+	recovery, err := readRecoveryFile(s.Config)
+	if err != nil {
+		return err
+	}
+
+	rngSource = source
+	Rng = rand.New(rngSource)
+	neat.SetRng(Rng)
+
+	applyConfig(s.Config, recovery)
+	Ticks = s.Ticks
+	RunId = s.Run
+	nextAnimalId = s.NextAnimalId
+	TotalBirths = s.TotalBirths
+	TotalDeaths = s.TotalDeaths
+	TerrainMap = &Terrain{width: s.Terrain.Width, height: s.Terrain.Height, tiles: slices.Clone(s.Terrain.Tiles)}
+
+	FertileZones = nil
+	for _, zone := range s.FertileZones {
+		FertileZones = append(FertileZones, FertileZone{x: zone.X, y: zone.Y, radius: zone.Radius, fertility: zone.Fertility})
+	}
+
+	Animals = nil
+	for _, animal := range s.Animals {
+		Animals = append(Animals, restoreAnimal(animal))
+	}
+	newAnimals = nil
+	for _, animal := range s.NewAnimals {
+		newAnimals = append(newAnimals, restoreAnimal(animal))
+	}
+
+	FoodBlocks = make(map[int]*Food)
+	for _, f := range s.Food {
+		food := InitFood(f.X, f.Y)
+		food.fp = f.Fp
+		food.maxFp = f.MaxFp
+		food.eatenThisTick = f.EatenThisTick
+		food.ticksToGrow = f.TicksToGrow
+		if food.fp <= 0 {
+			food.currSpriteIdx = 1
+		}
+		FoodBlocks[HashCoords(f.X, f.Y)] = food
+	}
+
+	Lineage = NewLineageStore()
+	for _, r := range s.Lineage {
+		Lineage.add(r)
+	}
+
+	Champions = &HallOfFame{}
+	if s.HallOfFame != nil {
+		Champions = s.HallOfFame.Copy()
+	}
+
+	nextSpeciesId = s.NextSpeciesId
+	SpeciesList = nil
+	for _, species := range s.Species {
+		SpeciesList = append(SpeciesList, &Species{
+			id:             species.Id,
+			representative: species.Representative.Copy(),
+			members:        species.Members,
+		})
+	}
+
+	Corpses = make(map[int]*Corpse)
+	for _, c := range s.Corpses {
+		Corpses[HashCoords(c.X, c.Y)] = &Corpse{
+			x:            c.X,
+			y:            c.Y,
+			w:            c.W,
+			h:            c.H,
+			meat:         c.Meat,
+			maxMeat:      c.MaxMeat,
+			ticksToDecay: c.TicksToDecay,
+			animalType:   c.AnimalType,
+			sprite:       loadAnimalSprite(c.AnimalType),
+		}
+	}
+
+	updateCrowding(false)
+	return nil
+}
+
+// Decodes a snapshot, migrating it to the current version if it was
+// written by an older one.
+func decodeSnapshot(data []byte) (*Snapshot, error) {
+	// Parameters an older version didn't have take their default value.
+	s := &Snapshot{Config: DefaultConfig()}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Version < OLDEST_SNAPSHOT_VERSION || s.Version > SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d to %d", s.Version, OLDEST_SNAPSHOT_VERSION, SNAPSHOT_VERSION)
+	}
+
+	if s.Version == 8 {
+		// A single minimum applied to both animal types, which weren't
+		// limited in any other way.
+		var old struct {
+			Config struct {
+				MinPopulation int `json:"minPopulation"`
+			} `json:"config"`
+		}
+		if err := json.Unmarshal(data, &old); err != nil {
+			return nil, err
+		}
+		s.Config.Population = PopulationControls{
+			Prey:    PopulationControl{Min: old.Config.MinPopulation},
+			Hunters: PopulationControl{Min: old.Config.MinPopulation},
+		}
+		s.Version = 9
+	}
+	if s.Version == 9 {
+		// Totals weren't kept, but the lineage still held every animal that
+		// ever lived, the founders being the ones born at tick 0.
+		for _, r := range s.Lineage {
+			if r.BirthTick > 0 {
+				s.TotalBirths++
+			}
+			if !r.IsAlive() {
+				s.TotalDeaths++
+			}
+		}
+		s.Version = 10
+	}
+	if s.Version == 10 {
+		// Runs didn't have ids, the snapshot goes on as a run of its own.
+		s.Run = newRunId()
+		s.Version = 11
+	}
+	return s, nil
+}
+
+func SaveWorld(w io.Writer) error {
+	s, err := TakeSnapshot()
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(s)
+}
+
+func LoadWorld(r io.Reader) error {
+	var data json.RawMessage
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return err
+	}
+	s, err := decodeSnapshot(data)
+	if err != nil {
+		return err
+	}
+	return RestoreSnapshot(s)
+}
+
+func SaveWorldFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return SaveWorld(file)
+}
+
+func LoadWorldFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return LoadWorld(file)
+}
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func snapshotJSON(t *testing.T) []byte {
+	t.Helper()
+	s, err := TakeSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLoadedSnapshotContinuesIdentically(t *testing.T) {
+	// Sprites are loaded relative to the repository root.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	const before, after = 150, 300
+	Seed(1)
+	InitGameConfig()
+	for range before {
+		Tick()
+	}
+	var saved bytes.Buffer
+	if err := SaveWorld(&saved); err != nil {
+		t.Fatal(err)
+	}
+	for range after {
+		Tick()
+	}
+	original := snapshotJSON(t)
+
+	if err := LoadWorld(&saved); err != nil {
+		t.Fatal(err)
+	}
+	for range after {
+		Tick()
+	}
+	resumed := snapshotJSON(t)
+
+	if !bytes.Equal(original, resumed) {
+		t.Errorf("world resumed at tick %d diverged from the original run by tick %d", before, before+after)
+	}
+}
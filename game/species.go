@@ -0,0 +1,79 @@
+package game
+
+import (
+	"example.com/artificial-life/neat"
+)
+
+// Animals belong to the same species when their brains are close enough to
+// the brain of the animal which founded it.
+type Species struct {
+	id             int
+	representative *neat.Genome
+	members        int
+}
+
+var SpeciesList []*Species
+var nextSpeciesId = 1
+
+func findSpecies(id int) *Species {
+	for _, s := range SpeciesList {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// Puts the animal in the species of its parent if it is still compatible
+// with it, otherwise in the first compatible one, founding a new species
+// if there is none.
+func (a *Animal) assignSpecies(parentSpecies int) {
+	species := findSpecies(parentSpecies)
+	if species == nil || neat.CompatibilityDistance(species.representative, a.brain) > Game.SpeciesThreshold {
+		species = nil
+		for _, s := range SpeciesList {
+			if neat.CompatibilityDistance(s.representative, a.brain) <= Game.SpeciesThreshold {
+				species = s
+				break
+			}
+		}
+	}
+
+	if species == nil {
+		species = &Species{id: nextSpeciesId, representative: a.brain.Copy()}
+		nextSpeciesId++
+		SpeciesList = append(SpeciesList, species)
+	}
+
+	species.members++
+	a.species = species.id
+}
+
+func (a *Animal) leaveSpecies() {
+	species := findSpecies(a.species)
+	if species == nil {
+		return
+	}
+
+	species.members--
+	if species.members <= 0 {
+		for i, s := range SpeciesList {
+			if s == species {
+				SpeciesList = append(SpeciesList[:i], SpeciesList[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (a *Animal) GetSpecies() int {
+	return a.species
+}
+
+func (s *Species) GetId() int {
+	return s.id
+}
+
+func (s *Species) GetMembers() int {
+	return s.members
+}
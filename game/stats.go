@@ -0,0 +1,212 @@
+package game
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Running totals, mainly so that statistics can tell how many animals were
+// born or died between two samples.
+var TotalBirths int
+var TotalDeaths int
+
+type Summary struct {
+	Mean float64 `json:"mean"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+func summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	s := Summary{Min: math.Inf(1), Max: math.Inf(-1)}
+	for _, v := range values {
+		s.Mean += v
+		s.Min = min(s.Min, v)
+		s.Max = max(s.Max, v)
+	}
+	s.Mean /= float64(len(values))
+	return s
+}
+
+type StatsSample struct {
+	Tick         int         `json:"tick"`
+	Population   int         `json:"population"`
+	Prey         int         `json:"prey"`
+	Hunters      int         `json:"hunters"`
+	Carnivores   int         `json:"carnivores"`
+	Species      int         `json:"species"`
+	SpeciesSizes map[int]int `json:"speciesSizes"`
+	Hp           Summary     `json:"hp"`
+	Age          Summary     `json:"age"`
+	Fitness      Summary     `json:"fitness"`
+	Neurons      Summary     `json:"neurons"`
+	Links        Summary     `json:"links"`
+	Plants       int         `json:"plants"`
+	PlantFp      float64     `json:"plantFp"`
+	Corpses      int         `json:"corpses"`
+	Births       int         `json:"births"`
+	Deaths       int         `json:"deaths"`
+}
+
+func SampleStats() StatsSample {
+	s := StatsSample{Tick: Ticks, Population: len(Animals), SpeciesSizes: make(map[int]int)}
+
+	var hp, age, fitness, neurons, links []float64
+	for _, a := range Animals {
+		if a.animalType == PREY {
+			s.Prey++
+		} else {
+			s.Hunters++
+		}
+		if a.diet.IsCarnivore() {
+			s.Carnivores++
+		}
+		s.SpeciesSizes[a.species]++
+		hp = append(hp, float64(a.hp))
+		age = append(age, float64(a.GetAge()))
+		fitness = append(fitness, a.fitness)
+		neurons = append(neurons, float64(a.brain.GetNumberOfNeurons()))
+		links = append(links, float64(a.brain.GetNumberOfLinks()))
+	}
+	s.Species = len(s.SpeciesSizes)
+	s.Hp = summarize(hp)
+	s.Age = summarize(age)
+	s.Fitness = summarize(fitness)
+	s.Neurons = summarize(neurons)
+	s.Links = summarize(links)
+
+	for _, food := range FoodBlocks {
+		if food.fp > 0 {
+			s.Plants++
+			s.PlantFp += food.fp
+		}
+	}
+	s.Corpses = len(Corpses)
+
+	return s
+}
+
+type StatsWriter interface {
+	Write(s StatsSample) error
+	Close() error
+}
+
+type csvStatsWriter struct {
+	closer io.Closer
+	w      *csv.Writer
+	header bool
+}
+
+var csvStatsHeader = []string{
+	"tick", "population", "prey", "hunters", "carnivores", "species",
+	"hp_mean", "hp_min", "hp_max",
+	"age_mean", "age_min", "age_max",
+	"fitness_mean", "fitness_min", "fitness_max",
+	"neurons_mean", "neurons_min", "neurons_max",
+	"links_mean", "links_min", "links_max",
+	"plants", "plant_fp", "corpses", "births", "deaths",
+}
+
+func (c *csvStatsWriter) Write(s StatsSample) error {
+	if !c.header {
+		if err := c.w.Write(csvStatsHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+
+	itoa := strconv.Itoa
+	ftoa := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	row := []string{
+		itoa(s.Tick), itoa(s.Population), itoa(s.Prey), itoa(s.Hunters), itoa(s.Carnivores), itoa(s.Species),
+	}
+	for _, summary := range []Summary{s.Hp, s.Age, s.Fitness, s.Neurons, s.Links} {
+		row = append(row, ftoa(summary.Mean), ftoa(summary.Min), ftoa(summary.Max))
+	}
+	row = append(row, itoa(s.Plants), ftoa(s.PlantFp), itoa(s.Corpses), itoa(s.Births), itoa(s.Deaths))
+
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvStatsWriter) Close() error {
+	return c.closer.Close()
+}
+
+type jsonStatsWriter struct {
+	closer io.Closer
+	enc    *json.Encoder
+}
+
+func (j *jsonStatsWriter) Write(s StatsSample) error {
+	return j.enc.Encode(s)
+}
+
+func (j *jsonStatsWriter) Close() error {
+	return j.closer.Close()
+}
+
+// The format is chosen from the extension of the file: CSV for ".csv",
+// JSON Lines for anything else.
+func CreateStatsWriter(path string) (StatsWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(path) == ".csv" {
+		return &csvStatsWriter{closer: file, w: csv.NewWriter(file)}, nil
+	}
+	return &jsonStatsWriter{closer: file, enc: json.NewEncoder(file)}, nil
+}
+
+type StatsCollector struct {
+	interval   int
+	writer     StatsWriter
+	lastTick   int
+	lastBirths int
+	lastDeaths int
+	Last       StatsSample
+}
+
+func NewStatsCollector(interval int, writer StatsWriter) *StatsCollector {
+	return &StatsCollector{
+		interval:   max(1, interval),
+		writer:     writer,
+		lastTick:   Ticks,
+		lastBirths: TotalBirths,
+		lastDeaths: TotalDeaths,
+	}
+}
+
+// Meant to be called after every tick, it takes a sample every interval
+// ticks.
+func (c *StatsCollector) Update() error {
+	if Ticks-c.lastTick < c.interval {
+		return nil
+	}
+
+	s := SampleStats()
+	s.Births = TotalBirths - c.lastBirths
+	s.Deaths = TotalDeaths - c.lastDeaths
+	c.lastTick = Ticks
+	c.lastBirths = TotalBirths
+	c.lastDeaths = TotalDeaths
+	c.Last = s
+
+	if c.writer == nil {
+		return nil
+	}
+	return c.writer.Write(s)
+}
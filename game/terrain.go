@@ -0,0 +1,197 @@
+package game
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+
+	"github.com/gopxl/pixel/v2"
+)
+
+type TileType uint8
+
+const (
+	GRASS TileType = iota
+	SAND
+	WATER
+	ROCK
+)
+
+const (
+	TILE_SIZE = 32.0
+)
+
+var tileMovementCost = [...]float64{GRASS: 1, SAND: 1.5, WATER: 4, ROCK: math.Inf(1)}
+var tileColors = [...]color.RGBA{
+	GRASS: {34, 139, 34, 255},
+	SAND:  {222, 200, 140, 255},
+	WATER: {40, 90, 200, 255},
+	ROCK:  {110, 110, 110, 255},
+}
+
+type Terrain struct {
+	width  int
+	height int
+	tiles  []TileType
+}
+
+var TerrainMap = &Terrain{}
+
+func GenerateTerrain(width, height int) *Terrain {
+	t := &Terrain{width: width, height: height, tiles: make([]TileType, width*height)}
+
+	noise := newValueNoise(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			elevation := noise.at(float64(x), float64(y))
+			var tile TileType
+			switch {
+			case elevation < 0.3:
+				tile = WATER
+			case elevation < 0.35:
+				tile = SAND
+			case elevation > 0.72:
+				tile = ROCK
+			default:
+				tile = GRASS
+			}
+			t.tiles[y*width+x] = tile
+		}
+	}
+
+	return t
+}
+
+// Builds a terrain from an image where each pixel is a tile, the tile type
+// being the one whose color is the closest to the pixel's. The image's top
+// row is the northern edge of the map.
+func LoadTerrain(path string) (*Terrain, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	t := &Terrain{width: bounds.Dx(), height: bounds.Dy()}
+	t.tiles = make([]TileType, t.width*t.height)
+	for y := 0; y < t.height; y++ {
+		for x := 0; x < t.width; x++ {
+			c := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Max.Y-1-y)).(color.RGBA)
+			t.tiles[y*t.width+x] = closestTile(c)
+		}
+	}
+
+	return t, nil
+}
+
+func closestTile(c color.RGBA) TileType {
+	best := GRASS
+	bestDist := math.Inf(1)
+	for tile, tc := range tileColors {
+		dr := float64(c.R) - float64(tc.R)
+		dg := float64(c.G) - float64(tc.G)
+		db := float64(c.B) - float64(tc.B)
+		if d := dr*dr + dg*dg + db*db; d < bestDist {
+			best = TileType(tile)
+			bestDist = d
+		}
+	}
+	return best
+}
+
+func (t *Terrain) TileAt(x, y float64) TileType {
+	x, y = WrapPos(x, y)
+	if Game.Topology == WALLS && !IsInsideWorld(x, y) {
+		return ROCK
+	}
+
+	tx := int(math.Floor(x / TILE_SIZE))
+	ty := int(math.Floor(y / TILE_SIZE))
+	if tx < 0 || ty < 0 || tx >= t.width || ty >= t.height {
+		return GRASS
+	}
+	return t.tiles[ty*t.width+tx]
+}
+
+func (t *Terrain) MovementCost(x, y float64) float64 {
+	return tileMovementCost[t.TileAt(x, y)]
+}
+
+func (t *Terrain) IsPassable(x, y float64) bool {
+	return !math.IsInf(t.MovementCost(x, y), 1)
+}
+
+func (t *Terrain) BlocksVision(x, y float64) bool {
+	return t.TileAt(x, y) == ROCK
+}
+
+func (t *Terrain) IsFertile(x, y float64) bool {
+	tile := t.TileAt(x, y)
+	return tile == GRASS || tile == SAND
+}
+
+// One pixel per tile, meant to be drawn scaled by TILE_SIZE.
+func (t *Terrain) Picture() *pixel.PictureData {
+	pic := pixel.MakePictureData(pixel.R(0, 0, float64(t.width), float64(t.height)))
+	for y := 0; y < t.height; y++ {
+		for x := 0; x < t.width; x++ {
+			pic.Pix[y*pic.Stride+x] = tileColors[t.tiles[y*t.width+x]]
+		}
+	}
+	return pic
+}
+
+type valueNoise struct {
+	octaves [][]float64
+	sizes   []int
+}
+
+func newValueNoise(width, height int) *valueNoise {
+	n := &valueNoise{}
+	for cell := 16; cell >= 2; cell /= 2 {
+		size := max(width, height)/cell + 2
+		lattice := make([]float64, size*size)
+		for i := range lattice {
+			lattice[i] = Rng.Float64()
+		}
+		n.octaves = append(n.octaves, lattice)
+		n.sizes = append(n.sizes, cell)
+	}
+	return n
+}
+
+func (n *valueNoise) at(x, y float64) float64 {
+	res := 0.0
+	amplitude := 1.0
+	total := 0.0
+	for i, lattice := range n.octaves {
+		cell := float64(n.sizes[i])
+		size := int(math.Sqrt(float64(len(lattice))))
+
+		gx, gy := x/cell, y/cell
+		x0, y0 := int(gx), int(gy)
+		fx, fy := smoothstep(gx-float64(x0)), smoothstep(gy-float64(y0))
+
+		v00 := lattice[y0*size+x0]
+		v10 := lattice[y0*size+x0+1]
+		v01 := lattice[(y0+1)*size+x0]
+		v11 := lattice[(y0+1)*size+x0+1]
+		top := v00 + (v10-v00)*fx
+		bottom := v01 + (v11-v01)*fx
+
+		res += (top + (bottom-top)*fy) * amplitude
+		total += amplitude
+		amplitude /= 2
+	}
+	return res / total
+}
+
+func smoothstep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
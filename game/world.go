@@ -0,0 +1,84 @@
+package game
+
+import (
+	"math"
+)
+
+type Topology uint8
+
+const (
+	WALLS Topology = iota
+	TORUS
+	SOFT
+)
+
+const (
+	SOFT_BORDER_WIDTH = 256.0
+	SOFT_BORDER_PUSH  = 4.0
+)
+
+func IsInsideWorld(x, y float64) bool {
+	return x >= 0 && y >= 0 && x < Game.WorldSize && y < Game.WorldSize
+}
+
+// On a torus every position is mapped back into the world, any other
+// topology leaves positions untouched.
+func WrapPos(x, y float64) (float64, float64) {
+	if Game.Topology != TORUS || Game.WorldSize <= 0 {
+		return x, y
+	}
+
+	x = math.Mod(x, Game.WorldSize)
+	if x < 0 {
+		x += Game.WorldSize
+	}
+	y = math.Mod(y, Game.WorldSize)
+	if y < 0 {
+		y += Game.WorldSize
+	}
+	return x, y
+}
+
+// Of all the copies of (x, y) on a torus, returns the one closest to the
+// reference point.
+func nearestImage(refX, refY, x, y float64) (float64, float64) {
+	if Game.Topology != TORUS {
+		return x, y
+	}
+
+	half := Game.WorldSize / 2
+	switch {
+	case x-refX > half:
+		x -= Game.WorldSize
+	case refX-x > half:
+		x += Game.WorldSize
+	}
+	switch {
+	case y-refY > half:
+		y -= Game.WorldSize
+	case refY-y > half:
+		y += Game.WorldSize
+	}
+	return x, y
+}
+
+func snapToCell(x, y float64) (float64, float64) {
+	x, y = WrapPos(x, y)
+	return x - math.Mod(x, 16.0), y - math.Mod(y, 16.0)
+}
+
+// Pushes back animals which wander into the soft border, the deeper they
+// go the harder they get pushed.
+func borderPush(x, y float64) (dx, dy float64) {
+	depth := func(v float64) float64 {
+		if v < SOFT_BORDER_WIDTH {
+			return (SOFT_BORDER_WIDTH - v) / SOFT_BORDER_WIDTH
+		}
+		if v > Game.WorldSize-SOFT_BORDER_WIDTH {
+			return -(v - (Game.WorldSize - SOFT_BORDER_WIDTH)) / SOFT_BORDER_WIDTH
+		}
+		return 0
+	}
+
+	return depth(x) * SOFT_BORDER_PUSH, depth(y) * SOFT_BORDER_PUSH
+}
@@ -0,0 +1,188 @@
+//go:build cgo && !nogui
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"example.com/artificial-life/game"
+	"example.com/artificial-life/neat"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"golang.org/x/image/colornames"
+)
+
+const (
+	INSPECTOR_WIDTH = 340.0
+	// How close to an animal, in world units, a click has to be to select
+	// it.
+	SELECT_RADIUS = 24.0
+)
+
+// Shows the animal clicked on in a side panel, its brain lighting up as it
+// thinks, while the camera follows it.
+type inspector struct {
+	selected int
+	imd      *imdraw.IMDraw
+	txt      *text.Text
+}
+
+func newInspector() *inspector {
+	return &inspector{
+		imd: imdraw.New(nil),
+		txt: text.New(pixel.ZV, text.Atlas7x13),
+	}
+}
+
+func (in *inspector) animal() *game.Animal {
+	if in.selected == 0 {
+		return nil
+	}
+	a := game.FindAnimal(in.selected)
+	if a == nil {
+		in.selected = 0
+	}
+	return a
+}
+
+func (in *inspector) handleInput(win *opengl.Window, cam pixel.Matrix) {
+	if win.JustPressed(pixel.KeyEscape) {
+		in.selected = 0
+	}
+	if !win.JustPressed(pixel.MouseButtonLeft) {
+		return
+	}
+	mouse := win.MousePosition()
+	if in.selected != 0 && mouse.X > win.Bounds().Max.X-INSPECTOR_WIDTH {
+		return
+	}
+
+	click := cam.Unproject(mouse)
+	in.selected = 0
+	closest := SELECT_RADIUS
+	for _, a := range game.Animals {
+		x, y := a.GetPos()
+		if d := click.To(pixel.V(x, y)).Len(); d < closest {
+			closest = d
+			in.selected = a.GetId()
+		}
+	}
+}
+
+// Where the camera should look, if it follows an animal.
+func (in *inspector) follow() (pixel.Vec, bool) {
+	a := in.animal()
+	if a == nil {
+		return pixel.ZV, false
+	}
+	x, y := a.GetPos()
+	return pixel.V(x, y), true
+}
+
+// Marks the selected animal and the rays it sees through, in world
+// coordinates.
+func (in *inspector) drawWorld(win *opengl.Window) {
+	a := in.animal()
+	if a == nil {
+		return
+	}
+
+	in.imd.Clear()
+	x, y := a.GetPos()
+	in.imd.Color = colornames.Yellow
+	in.imd.Push(pixel.V(x, y))
+	in.imd.Circle(14, 2)
+
+	drawVision(in.imd, a)
+	in.imd.Draw(win)
+}
+
+// Draws the side panel in window coordinates.
+func (in *inspector) drawPanel(win *opengl.Window) {
+	a := in.animal()
+	if a == nil {
+		return
+	}
+	info := a.Info(true)
+	bounds := win.Bounds()
+	panel := pixel.R(bounds.Max.X-INSPECTOR_WIDTH, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+
+	in.imd.Clear()
+	in.imd.Color = color.RGBA{20, 20, 28, 230}
+	in.imd.Push(panel.Min, panel.Max)
+	in.imd.Rectangle(0)
+
+	in.txt.Clear()
+	in.txt.Orig = pixel.V(panel.Min.X+12, panel.Max.Y-20)
+	in.txt.Dot = in.txt.Orig
+	fmt.Fprintf(in.txt, "Animal %d (%s)  [Esc] to close\n\n", info.Id, info.Type)
+	fmt.Fprintf(in.txt, "hp       %d (hungry in %d ticks)\n", info.Hp, info.TicksUntilHurt)
+	fmt.Fprintf(in.txt, "fitness  %.1f of %.1f\n", info.Fitness, info.FitnessGoal)
+	fmt.Fprintf(in.txt, "age      %d ticks\n", info.Age)
+	fmt.Fprintf(in.txt, "species  %d, generation %d\n", info.Species, info.Generation)
+	fmt.Fprintf(in.txt, "diet     plants %.2f, meat %.2f\n\n", info.PlantEfficiency, info.MeatEfficiency)
+	fmt.Fprintln(in.txt, "ray  plant  meat")
+	for i, r := range a.Rays() {
+		fmt.Fprintf(in.txt, "%3d  %5s  %4s\n", i, rayStep(r.Plant), rayStep(r.Meat))
+	}
+
+	top := in.txt.Dot.Y - 10
+	area := pixel.R(panel.Min.X+10, panel.Min.Y+10, panel.Max.X-10, top)
+	if area.H() > 40 {
+		in.drawBrain(info.Brain, area)
+	}
+
+	in.imd.Draw(win)
+	in.txt.Draw(win, pixel.IM)
+}
+
+func rayStep(step int) string {
+	if step == 0 {
+		return "-"
+	}
+	return fmt.Sprint(step)
+}
+
+// Fits the brain's diagram in the area, neurons coloured by how active they
+// are.
+func (in *inspector) drawBrain(brain *neat.Genome, area pixel.Rect) {
+	d := brain.Diagram()
+	scale := math.Min(area.W()/d.Width, area.H()/d.Height)
+	offset := area.Center().Sub(pixel.V(d.Width, d.Height).Scaled(scale / 2))
+	// Diagrams grow downwards, windows upwards.
+	at := func(x, y float64) pixel.Vec {
+		return pixel.V(offset.X+x*scale, offset.Y+(d.Height-y)*scale)
+	}
+
+	for _, e := range d.Edges {
+		if !e.Enabled {
+			continue
+		}
+		in.imd.Color = neat.EdgeColor(e.Weight, e.Enabled)
+		in.imd.Push(at(e.X1, e.Y1), at(e.X2, e.Y2))
+		in.imd.Line(math.Max(1, neat.EdgeWidth(e.Weight)*scale))
+	}
+	for _, n := range d.Nodes {
+		in.imd.Color = activationColor(n.Value)
+		in.imd.Push(at(n.X, n.Y))
+		in.imd.Circle(math.Max(2, neat.DIAGRAM_NODE_RADIUS*scale), 0)
+		in.imd.Color = neat.NodeColor(n.Kind)
+		in.imd.Push(at(n.X, n.Y))
+		in.imd.Circle(math.Max(2, neat.DIAGRAM_NODE_RADIUS*scale), 1)
+	}
+}
+
+// Dark when idle, bright yellow when strongly positive and blue when
+// negative.
+func activationColor(v float64) color.Color {
+	level := 1 - math.Exp(-math.Abs(v))
+	if v < 0 {
+		return pixel.RGB(0.15, 0.15+0.3*level, 0.2+0.8*level)
+	}
+	return pixel.RGB(0.15+0.85*level, 0.15+0.75*level, 0.15)
+}
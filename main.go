@@ -1,161 +1,361 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"image"
-	"math"
+	"math/rand/v2"
+	"net/http"
 	"os"
-	"sync"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"example.com/artificial-life/game"
+	"example.com/artificial-life/server"
 
 	_ "image/png"
 
 	"github.com/gopxl/pixel/v2"
-	"github.com/gopxl/pixel/v2/backends/opengl"
-	"golang.org/x/image/colornames"
 )
 
-func initProgram() {
-	game.InitGameConfig()
+var autosaver *game.Autosaver
+var eventLog *game.EventLog
+var replay *game.Replay
+var stats *game.StatsCollector
+var statsWriter game.StatsWriter
+var metrics *server.Metrics
+var controller *server.Controller
+var stream *server.Stream
+var muxes = make(map[string]*http.ServeMux)
+
+// Parameters and seed given to a resumed world, recorded in its event log.
+var resumedConfig *game.GameConfig
+var resumedSeed uint64
+
+// Tick at which the simulation stops, 0 meaning never.
+var stopTick int
+var startTick int
+var peakPopulation int
+
+// Handlers sharing an address are served by the same server.
+func handle(addr, pattern string, handler http.Handler) {
+	mux := muxes[addr]
+	if mux == nil {
+		mux = http.NewServeMux()
+		muxes[addr] = mux
+	}
+	mux.Handle(pattern, handler)
 }
 
-func loadPicture(path string) (pixel.Picture, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+func startServers() {
+	for addr, mux := range muxes {
+		go func() {
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				fmt.Println("server on", addr, "stopped:", err)
+			}
+		}()
 	}
-	defer file.Close()
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
+}
+
+func loadConfig() (game.GameConfig, error) {
+	return loadConfigOver(game.DefaultConfig())
+}
+
+// The parameters from -config, or else base, overridden by the ones from
+// -set.
+func loadConfigOver(base game.GameConfig) (game.GameConfig, error) {
+	config := base
+	if configPath != "" {
+		var err error
+		config, err = game.LoadConfigFile(configPath)
+		if err != nil {
+			return config, err
+		}
 	}
-	return pixel.PictureDataFromImage(img), nil
+	for _, override := range configOverrides {
+		if err := config.Set(override); err != nil {
+			return config, err
+		}
+	}
+	return config, config.Validate()
 }
 
-func run() {
-	cfg := opengl.WindowConfig{
-		Title:  "Artificial Life",
-		Bounds: pixel.R(0, 0, 1024, 768),
-		VSync:  true,
+// Creates a new world, or restores one when from is a checkpoint, a
+// snapshot or a directory of checkpoints.
+func initWorld(from string) error {
+	if from == "" {
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		// Read first, as a brain taken from a checkpoint loads its world.
+		brain, err := readBrain()
+		if err != nil {
+			return err
+		}
+		if seed == 0 {
+			seed = rand.Uint64()
+		}
+		fmt.Println("Seed", seed)
+		game.Seed(seed)
+		if err := game.InitWorld(config); err != nil {
+			return err
+		}
+		if err := game.LoadRecoveryFile(); err != nil {
+			return err
+		}
+		if brain != nil {
+			return game.SeedBrains(brain)
+		}
+		return nil
 	}
-	win, err := opengl.NewWindow(cfg)
+
+	info, err := os.Stat(from)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer win.Destroy()
-
-	win.Clear(colornames.Skyblue)
+	if info.IsDir() {
+		path, err := game.ResumeLatestCheckpoint(from)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Resuming from", path)
+		return nil
+	}
+	checkpoint, err := game.IsCheckpoint(from)
+	if err != nil {
+		return err
+	}
+	load := game.LoadWorldFile
+	if checkpoint {
+		load = game.LoadCheckpoint
+	}
+	if err := load(from); err != nil {
+		return err
+	}
+	fmt.Println("Resuming from", from)
+	return nil
+}
 
-	var (
-		camPos       = pixel.ZV
-		camSpeed     = 500.0
-		baseCamSpeed     = 500.0
-		camZoom      = 1.0
-		camZoomSpeed = 1.2
-		// trees        []*pixel.Sprite
-		// matrices     []pixel.Matrix
-	)
+// Gives a resumed world the parameters and seed from the command line, if
+// any.
+func reconfigure() error {
+	if configPath != "" || len(configOverrides) > 0 {
+		config, err := loadConfigOver(game.Game)
+		if err != nil {
+			return err
+		}
+		if err := game.Reconfigure(config); err != nil {
+			return err
+		}
+		resumedConfig = &config
+	}
+	if seed != 0 {
+		fmt.Println("Seed", seed)
+		game.Seed(seed)
+		resumedSeed = seed
+	}
+	return nil
+}
 
-	angle := 0.0
-	last := time.Now()
-	lastTick := time.Now()
-	tickDuration := time.Duration(float64(1/float64(game.Game.TicksPerSecond)) * float64(time.Second))
-	camPos.X = 2048
-	camPos.Y = 2048
+func initOutputs() error {
+	if eventsPath != "" {
+		var err error
+		eventLog, err = game.OpenEventLog(eventsPath)
+		if err != nil {
+			return err
+		}
+		game.EventSink = eventLog.Record
+		game.RecordRun(resumedConfig, resumedSeed)
+	}
 
-	for !win.Closed() {
-		cam := pixel.IM.Scaled(camPos, camZoom).Moved(win.Bounds().Center().Sub(camPos))
-		win.SetMatrix(cam)
+	if statsPath != "" {
+		var err error
+		statsWriter, err = game.CreateStatsWriter(statsPath)
+		if err != nil {
+			return err
+		}
+	}
+	stats = game.NewStatsCollector(statsInterval, statsWriter)
 
-		dt := time.Since(last).Seconds()
-		last = time.Now()
+	if metricsAddr != "" {
+		metrics = server.NewMetrics()
+		handle(metricsAddr, "/metrics", metrics)
+	}
 
-		shouldUpdate := false
-		if last.Sub(lastTick) >= tickDuration {
-			lastTick = lastTick.Add(tickDuration)
-			shouldUpdate = true
+	// Replays start from the first checkpoint of the run.
+	policy := autosavePolicy()
+	if eventLog != nil || policy.EveryTicks > 0 || policy.Every > 0 {
+		if err := game.WriteFirstCheckpoint(policy.Dir); err != nil {
+			return err
 		}
+	}
+	autosaver = game.NewAutosaver(policy)
+	return nil
+}
 
-		camZoom *= math.Pow(camZoomSpeed, win.MouseScroll().Y)
-		camSpeed = baseCamSpeed / camZoom
-
-		win.Clear(colornames.Forestgreen)
+func autosavePolicy() game.AutosavePolicy {
+	return game.AutosavePolicy{
+		Dir:        autosaveDir,
+		EveryTicks: autosaveTicks,
+		Every:      time.Duration(autosaveMinutes * float64(time.Minute)),
+		Keep:       autosaveKeep,
+	}
+}
 
-		if win.Pressed(pixel.KeyDown) {
-			camPos.Y -= camSpeed * dt
-		}
-		if win.Pressed(pixel.KeyUp) {
-			camPos.Y += camSpeed * dt
+func closeOutputs() {
+	if autosaver != nil {
+		if policy := autosavePolicy(); policy.EveryTicks > 0 || policy.Every > 0 {
+			if _, err := game.WriteCheckpoint(policy.Dir); err != nil {
+				fmt.Println("could not write the last checkpoint:", err)
+			} else if err := game.PruneCheckpoints(policy.Dir, policy.Keep); err != nil {
+				fmt.Println("could not prune checkpoints:", err)
+			}
 		}
-		if win.Pressed(pixel.KeyLeft) {
-			camPos.X -= camSpeed * dt
+	}
+	if statsWriter != nil {
+		statsWriter.Close()
+	}
+	if outDir != "" {
+		if err := writeRunSummary(filepath.Join(outDir, RUN_SUMMARY_FILE)); err != nil {
+			fmt.Println("could not write the summary of the run:", err)
 		}
-		if win.Pressed(pixel.KeyRight) {
-			camPos.X += camSpeed * dt
+	}
+	if hallOfFamePath != "" {
+		if err := game.Champions.WithLiving().WriteFile(hallOfFamePath); err != nil {
+			fmt.Println("could not write the hall of fame:", err)
 		}
-
-		for _, food := range game.FoodBlocks {
-
-			x, y := food.GetPos()
-			mat := pixel.IM
-			mat = mat.Rotated(pixel.ZV, angle)
-			mat = mat.Moved(pixel.Vec{X: x, Y: y})
-
-			food.GetCurrSprite().Draw(win, mat)
+	}
+	if newickPath != "" {
+		if err := writeNewick(newickPath); err != nil {
+			fmt.Println("could not write the phylogenetic tree:", err)
 		}
-
-		if shouldUpdate {
-			sum := 0
-			wg := new(sync.WaitGroup)
-			for _, animal := range game.Animals {
-				wg.Add(1)
-				sum += animal.GetNumberOfNeurons()
-				go animal.Update(wg)
-			}
-			wg.Wait()
-			fmt.Println(sum, float64(sum) / float64(len(game.Animals)))
+	}
+	if eventLog != nil {
+		if err := eventLog.Close(); err != nil {
+			fmt.Println("could not write events:", err)
 		}
+	}
+}
 
-		for _, animal := range game.Animals {
+func initController() {
+	controller = server.NewController(tick, autosaveDir, float64(game.Game.TicksPerSecond))
+	if apiAddr != "" {
+		handle(apiAddr, "/api/", controller.Handler())
+	}
 
-			tickProportion := float64(last.Sub(lastTick)) / float64(tickDuration)
-			var dx, dy float64
+	if viewerAddr != "" {
+		stream = server.NewStream()
+		handle(viewerAddr, "/stream", stream)
+		handle(viewerAddr, "GET /{$}", http.HandlerFunc(server.ServeViewer))
+	}
+}
 
-			dx = math.Cos(animal.GetTheta()) * animal.GetSpeed() * float64(1/float64(20.0)) * tickProportion
-			dy = math.Sin(animal.GetTheta()) * animal.GetSpeed() * float64(1/float64(20.0)) * tickProportion
+func publishStream() {
+	if stream != nil {
+		stream.Publish()
+	}
+}
 
-			x, y := animal.GetPos()
+func shouldStop() bool {
+	return stopTick > 0 && game.Ticks >= stopTick
+}
 
-			mat := pixel.IM
-			mat = mat.Rotated(pixel.ZV, angle)
-			mat = mat.Moved(pixel.Vec{X: x + dx, Y: y + dy})
+func tick() {
+	if replay != nil {
+		if game.Ticks < replay.LastTick() {
+			if err := replay.Step(); err != nil {
+				fmt.Println(err)
+			}
+		}
+		return
+	}
 
-			animal.GetSprite().Draw(win, mat)
+	start := time.Now()
+	game.Tick()
+	peakPopulation = max(peakPopulation, len(game.Animals))
+	if metrics != nil {
+		metrics.ObserveTick(time.Since(start))
+		if game.Ticks%game.Game.TicksPerSecond == 0 {
+			metrics.Publish()
+		}
+	}
+	if err := autosaver.Update(); err != nil {
+		fmt.Println("autosave failed:", err)
+	}
+	lastSample := stats.Last
+	if err := stats.Update(); err != nil {
+		fmt.Println("could not write statistics:", err)
+	}
+	if sample := stats.Last; sample.Tick != lastSample.Tick {
+		fmt.Println(sample.Tick, sample.Population, sample.Species, sample.Neurons.Mean)
+	}
+	if eventLog != nil {
+		if err := eventLog.Flush(); err != nil {
+			fmt.Println("could not write events:", err)
+		}
+	}
+}
 
-			for _, papu := range game.Squares {
-				mat := pixel.IM
-				mat = mat.Rotated(pixel.ZV, angle)
-				mat = mat.Moved(pixel.Vec{X: papu.X, Y: papu.Y})
+func scrub(ticks int) {
+	if err := replay.Seek(game.Ticks + ticks); err != nil {
+		fmt.Println(err)
+	}
+}
 
-				animal.GetSprite().Draw(win, mat)
-			}
-			game.Squares = make([]pixel.Vec, 0)
+func runHeadless() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	next := time.Now()
+	for ctx.Err() == nil && !shouldStop() && !game.IsExtinct() {
+		controller.RunPending()
+		if controller.IsPaused() {
+			publishStream()
+			time.Sleep(10 * time.Millisecond)
+			next = time.Now()
+			continue
 		}
 
-
-		if shouldUpdate {
-			game.PruneDeadAnimals()
-			game.RegrowPlants()
+		tick()
+		publishStream()
+		next = next.Add(controller.TickDuration())
+		if wait := time.Until(next); wait > 0 {
+			time.Sleep(wait)
+		} else if wait < -time.Second {
+			// Too far behind to ever catch up, don't try to.
+			next = time.Now()
 		}
+	}
+}
 
-		win.Update()
+func writeNewick(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+	return game.Lineage.WriteNewick(file)
+}
+
+func loadPicture(path string) (pixel.Picture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return pixel.PictureDataFromImage(img), nil
 }
 
 func main() {
-	fmt.Println("Bienvenido")
-	initProgram()
-	opengl.Run(run)
+	if err := runCommand(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,267 @@
+package neat
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/fogleman/gg"
+)
+
+// A drawing of a genome: inputs on the left, outputs on the right and
+// hidden neurons in between, each one column further right than the
+// deepest neuron feeding it.
+type Diagram struct {
+	Width  float64
+	Height float64
+	Nodes  []DiagramNode
+	Edges  []DiagramEdge
+}
+
+type DiagramNode struct {
+	Id    int
+	Kind  string
+	Label string
+	X, Y  float64
+	// Activation level when the diagram was made.
+	Value float64
+}
+
+type DiagramEdge struct {
+	From, To int
+	X1, Y1   float64
+	X2, Y2   float64
+	Weight   float64
+	Enabled  bool
+}
+
+const (
+	DIAGRAM_NODE_RADIUS = 14.0
+	DIAGRAM_MARGIN      = 40.0
+	DIAGRAM_COLUMN      = 140.0
+	DIAGRAM_ROW         = 50.0
+)
+
+// Column of every neuron. Links may form cycles, so depths are only
+// relaxed as many times as there are neurons.
+func (g *Genome) columns() ([]int, int) {
+	depth := make([]int, len(g.neurons))
+	for range len(g.neurons) {
+		changed := false
+		for _, link := range g.links {
+			in, out := link.linkId.inputId, link.linkId.outputId
+			if out < g.numInputs+g.numOutputs || depth[in]+1 <= depth[out] || depth[in]+1 >= len(g.neurons) {
+				continue
+			}
+			depth[out] = depth[in] + 1
+			changed = true
+		}
+		if !changed {
+			break
+		}
+	}
+
+	last := 1
+	for id := g.numInputs + g.numOutputs; id < len(g.neurons); id++ {
+		last = max(last, depth[id]+1)
+	}
+	for id := g.numInputs; id < g.numInputs+g.numOutputs; id++ {
+		depth[id] = last
+	}
+	return depth, last
+}
+
+// Lays the genome out on a canvas just big enough for it.
+func (g *Genome) Diagram() Diagram {
+	depth, last := g.columns()
+	byColumn := make([][]int, last+1)
+	for id := range g.neurons {
+		byColumn[depth[id]] = append(byColumn[depth[id]], id)
+	}
+	rows := 1
+	for _, ids := range byColumn {
+		rows = max(rows, len(ids))
+	}
+
+	d := Diagram{
+		Width:  2*DIAGRAM_MARGIN + float64(last)*DIAGRAM_COLUMN,
+		Height: 2*DIAGRAM_MARGIN + float64(rows-1)*DIAGRAM_ROW,
+	}
+	positions := make(map[int][2]float64, len(g.neurons))
+	for column, ids := range byColumn {
+		top := DIAGRAM_MARGIN + float64(rows-len(ids))*DIAGRAM_ROW/2
+		for i, id := range ids {
+			x := DIAGRAM_MARGIN + float64(column)*DIAGRAM_COLUMN
+			y := top + float64(i)*DIAGRAM_ROW
+			positions[id] = [2]float64{x, y}
+			neuron := g.neurons[id]
+			d.Nodes = append(d.Nodes, DiagramNode{
+				Id:    id,
+				Kind:  g.neuronKind(id),
+				Label: fmt.Sprintf("%d %s", id, activationName(neuron.activation)),
+				X:     x,
+				Y:     y,
+				Value: neuron.value,
+			})
+		}
+	}
+
+	for _, link := range g.links {
+		from, to := positions[link.linkId.inputId], positions[link.linkId.outputId]
+		d.Edges = append(d.Edges, DiagramEdge{
+			From:    link.linkId.inputId,
+			To:      link.linkId.outputId,
+			X1:      from[0],
+			Y1:      from[1],
+			X2:      to[0],
+			Y2:      to[1],
+			Weight:  link.weight,
+			Enabled: link.isEnabled,
+		})
+	}
+	// Disabled links first, so enabled ones are drawn over them.
+	slices.SortStableFunc(d.Edges, func(a, b DiagramEdge) int {
+		switch {
+		case a.Enabled == b.Enabled:
+			return 0
+		case a.Enabled:
+			return 1
+		}
+		return -1
+	})
+	return d
+}
+
+// Thickness of a link, growing with its weight up to 5.
+func EdgeWidth(weight float64) float64 {
+	return 0.5 + 3.5*min(math.Abs(weight), 5)/5
+}
+
+// Blue for excitatory links, red for inhibitory ones and grey for disabled
+// ones, stronger weights being more opaque.
+func EdgeColor(weight float64, enabled bool) color.NRGBA {
+	if !enabled {
+		return color.NRGBA{160, 160, 160, 255}
+	}
+	alpha := uint8(90 + 165*min(math.Abs(weight), 5)/5)
+	if weight < 0 {
+		return color.NRGBA{215, 48, 39, alpha}
+	}
+	return color.NRGBA{33, 102, 172, alpha}
+}
+
+func NodeColor(kind string) color.NRGBA {
+	switch kind {
+	case "input":
+		return color.NRGBA{199, 233, 192, 255}
+	case "output":
+		return color.NRGBA{253, 208, 162, 255}
+	}
+	return color.NRGBA{222, 222, 222, 255}
+}
+
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Writes the genome as a Graphviz graph, e.g. for `dot -Tsvg`.
+func (g *Genome) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph genome_%d {\n", g.genomeId)
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  splines=true;")
+	fmt.Fprintln(bw, "  node [shape=circle, style=filled, fontname=\"Helvetica\", fontsize=10];")
+
+	depth, last := g.columns()
+	ranks := make(map[int][]int)
+	for id, neuron := range g.neurons {
+		fmt.Fprintf(bw, "  n%d [label=\"%d\\n%s\", fillcolor=\"%s\"];\n",
+			id, id, activationName(neuron.activation), hexColor(NodeColor(g.neuronKind(id))))
+		ranks[depth[id]] = append(ranks[depth[id]], id)
+	}
+	for rank := 0; rank <= last; rank++ {
+		ids := ranks[rank]
+		if len(ids) == 0 {
+			continue
+		}
+		fmt.Fprint(bw, "  { rank=same;")
+		for _, id := range ids {
+			fmt.Fprintf(bw, " n%d;", id)
+		}
+		fmt.Fprintln(bw, " }")
+	}
+
+	for _, link := range g.links {
+		c := EdgeColor(link.weight, link.isEnabled)
+		style := "solid"
+		if !link.isEnabled {
+			style = "dashed"
+		}
+		fmt.Fprintf(bw, "  n%d -> n%d [penwidth=%.2f, color=\"%s%02x\", style=%s, tooltip=\"%+.3f\"];\n",
+			link.linkId.inputId, link.linkId.outputId, EdgeWidth(link.weight), hexColor(c), c.A, style, link.weight)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// Draws the genome as an SVG image.
+func (g *Genome) WriteSVG(w io.Writer) error {
+	d := g.Diagram()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\">\n",
+		d.Width, d.Height, d.Width, d.Height)
+	fmt.Fprintf(bw, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+	for _, e := range d.Edges {
+		c := EdgeColor(e.Weight, e.Enabled)
+		dash := ""
+		if !e.Enabled {
+			dash = " stroke-dasharray=\"6 4\""
+		}
+		fmt.Fprintf(bw, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"%s\" stroke-opacity=\"%.2f\" stroke-width=\"%.2f\"%s><title>%d → %d: %+.3f</title></line>\n",
+			e.X1, e.Y1, e.X2, e.Y2, hexColor(c), float64(c.A)/255, EdgeWidth(e.Weight), dash, e.From, e.To, e.Weight)
+	}
+	for _, n := range d.Nodes {
+		fmt.Fprintf(bw, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.0f\" fill=\"%s\" stroke=\"#333333\"/>\n",
+			n.X, n.Y, DIAGRAM_NODE_RADIUS, hexColor(NodeColor(n.Kind)))
+		fmt.Fprintf(bw, "<text x=\"%.1f\" y=\"%.1f\" font-family=\"sans-serif\" font-size=\"10\" text-anchor=\"middle\" dominant-baseline=\"central\">%s</text>\n",
+			n.X, n.Y+DIAGRAM_NODE_RADIUS+9, n.Label)
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// Draws the genome as a PNG image.
+func (g *Genome) WritePNG(w io.Writer) error {
+	d := g.Diagram()
+	dc := gg.NewContext(int(math.Ceil(d.Width)), int(math.Ceil(d.Height)))
+	dc.SetColor(color.White)
+	dc.Clear()
+
+	for _, e := range d.Edges {
+		dc.SetColor(EdgeColor(e.Weight, e.Enabled))
+		dc.SetLineWidth(EdgeWidth(e.Weight))
+		if e.Enabled {
+			dc.SetDash()
+		} else {
+			dc.SetDash(6, 4)
+		}
+		dc.DrawLine(e.X1, e.Y1, e.X2, e.Y2)
+		dc.Stroke()
+	}
+
+	dc.SetDash()
+	dc.SetLineWidth(1)
+	for _, n := range d.Nodes {
+		dc.DrawCircle(n.X, n.Y, DIAGRAM_NODE_RADIUS)
+		dc.SetColor(NodeColor(n.Kind))
+		dc.FillPreserve()
+		dc.SetRGB255(51, 51, 51)
+		dc.Stroke()
+		dc.DrawStringAnchored(n.Label, n.X, n.Y+DIAGRAM_NODE_RADIUS+9, 0.5, 0.5)
+	}
+	return dc.EncodePNG(w)
+}
@@ -0,0 +1,119 @@
+package neat
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+var activations = map[string]Activation{
+	"sigmoid": sigmoid,
+	"relu":    relu,
+}
+
+func activationName(f Activation) string {
+	ptr := reflect.ValueOf(f).Pointer()
+	for name, activation := range activations {
+		if reflect.ValueOf(activation).Pointer() == ptr {
+			return name
+		}
+	}
+	return ""
+}
+
+type neuronJSON struct {
+	Id         int     `json:"id"`
+	Innovation int     `json:"innovation"`
+	Bias       float64 `json:"bias"`
+	Activation string  `json:"activation"`
+}
+
+type linkJSON struct {
+	In      int     `json:"in"`
+	Out     int     `json:"out"`
+	Weight  float64 `json:"weight"`
+	Enabled bool    `json:"enabled"`
+}
+
+type genomeJSON struct {
+	Id               int          `json:"id"`
+	NumInputs        int          `json:"numInputs"`
+	NumOutputs       int          `json:"numOutputs"`
+	NextId           int          `json:"nextId"`
+	NumActiveNeurons int          `json:"numActiveNeurons"`
+	Neurons          []neuronJSON `json:"neurons"`
+	Links            []linkJSON   `json:"links"`
+}
+
+func (g *Genome) MarshalJSON() ([]byte, error) {
+	data := genomeJSON{
+		Id:               g.genomeId,
+		NumInputs:        g.numInputs,
+		NumOutputs:       g.numOutputs,
+		NextId:           g.nextId,
+		NumActiveNeurons: g.numActiveNeurons,
+	}
+	for _, neuron := range g.neurons {
+		data.Neurons = append(data.Neurons, neuronJSON{
+			Id:         neuron.neuronId,
+			Innovation: neuron.innovation,
+			Bias:       neuron.bias,
+			Activation: activationName(neuron.activation),
+		})
+	}
+	for _, link := range g.links {
+		data.Links = append(data.Links, linkJSON{
+			In:      link.linkId.inputId,
+			Out:     link.linkId.outputId,
+			Weight:  link.weight,
+			Enabled: link.isEnabled,
+		})
+	}
+	return json.Marshal(data)
+}
+
+func (g *Genome) UnmarshalJSON(b []byte) error {
+	var data genomeJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	genome := Genome{
+		genomeId:         data.Id,
+		numInputs:        data.NumInputs,
+		numOutputs:       data.NumOutputs,
+		nextId:           data.NextId,
+		numActiveNeurons: data.NumActiveNeurons,
+	}
+	for i, n := range data.Neurons {
+		if n.Id != i {
+			return fmt.Errorf("neuron %d stored at position %d", n.Id, i)
+		}
+		activation, ok := activations[n.Activation]
+		if !ok {
+			return fmt.Errorf("unknown activation %q", n.Activation)
+		}
+		// Genomes saved before neurons were marked are marked by position,
+		// the way their links used to be matched.
+		if n.Innovation == 0 {
+			n.Innovation = n.Id
+		}
+		genome.neurons = append(genome.neurons, &NeuronGene{neuronId: n.Id, innovation: n.Innovation, bias: n.Bias, activation: activation})
+	}
+	for _, l := range data.Links {
+		if l.In < 0 || l.Out < 0 || l.In >= len(genome.neurons) || l.Out >= len(genome.neurons) {
+			return fmt.Errorf("link %d -> %d references a missing neuron", l.In, l.Out)
+		}
+		genome.links = append(genome.links, &LinkGene{
+			linkId:    LinkId{inputId: l.In, outputId: l.Out},
+			weight:    l.Weight,
+			isEnabled: l.Enabled,
+		})
+	}
+	if len(genome.neurons) < genome.numInputs+genome.numOutputs {
+		return fmt.Errorf("genome has %d neurons, expected at least %d", len(genome.neurons), genome.numInputs+genome.numOutputs)
+	}
+
+	*g = genome
+	return nil
+}
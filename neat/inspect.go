@@ -0,0 +1,63 @@
+package neat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Kind of a neuron, from its position in the genome.
+func (g *Genome) neuronKind(id int) string {
+	switch {
+	case id < g.numInputs:
+		return "input"
+	case id < g.numInputs+g.numOutputs:
+		return "output"
+	}
+	return "hidden"
+}
+
+// Writes a human readable description of the genome: its size, the
+// activations it uses and every link, disabled ones included.
+func (g *Genome) WriteSummary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	hidden := len(g.neurons) - g.numInputs - g.numOutputs
+	fmt.Fprintf(bw, "Genome %d\n", g.genomeId)
+	fmt.Fprintf(bw, "  neurons: %d inputs, %d outputs, %d hidden (%d active in total)\n",
+		g.numInputs, g.numOutputs, hidden, g.numActiveNeurons)
+	fmt.Fprintf(bw, "  links:   %d enabled of %d\n", g.GetNumberOfLinks(), len(g.links))
+
+	activations := make(map[string]int)
+	for _, neuron := range g.neurons {
+		activations[activationName(neuron.activation)]++
+	}
+	fmt.Fprintf(bw, "  activations:")
+	for _, name := range slices.Sorted(maps.Keys(activations)) {
+		fmt.Fprintf(bw, " %s %d", name, activations[name])
+	}
+	fmt.Fprintln(bw)
+
+	links := slices.Clone(g.links)
+	slices.SortFunc(links, func(a, b *LinkGene) int {
+		if a.linkId.inputId != b.linkId.inputId {
+			return a.linkId.inputId - b.linkId.inputId
+		}
+		return a.linkId.outputId - b.linkId.outputId
+	})
+
+	fmt.Fprintln(bw, "\nLinks")
+	for _, link := range links {
+		in, out := link.linkId.inputId, link.linkId.outputId
+		state := ""
+		if !link.isEnabled {
+			state = " (disabled)"
+		}
+		fmt.Fprintf(bw, "  %-6s %3d -> %-6s %3d  %+8.3f%s\n",
+			g.neuronKind(in), in, g.neuronKind(out), out, link.weight, state)
+	}
+
+	return bw.Flush()
+}
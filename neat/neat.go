@@ -1,15 +1,59 @@
 package neat
 
 import (
+	"encoding/binary"
+	"hash/fnv"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 	"slices"
 )
 
 type Activation func(float64) float64
 
+// Every random decision taken by the package comes from Rng, so that a
+// simulation can be replayed by seeding it.
+var Rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+func SetRng(r *rand.Rand) {
+	Rng = r
+}
+
+// Chances of each mutation, per link for the ones changing links and per
+// genome for the structural ones.
+type MutationRates struct {
+	Weight       float64 `json:"weight"`
+	WeightPower  float64 `json:"weightPower"`
+	ToggleLink   float64 `json:"toggleLink"`
+	AddLink      float64 `json:"addLink"`
+	RemoveLink   float64 `json:"removeLink"`
+	AddNeuron    float64 `json:"addNeuron"`
+	RemoveNeuron float64 `json:"removeNeuron"`
+}
+
+func DefaultMutationRates() MutationRates {
+	return MutationRates{
+		Weight:       0.10,
+		WeightPower:  0.25,
+		ToggleLink:   0.01,
+		AddLink:      0.2,
+		RemoveLink:   0.1,
+		AddNeuron:    0.05,
+		RemoveNeuron: 0.01,
+	}
+}
+
+var Rates = DefaultMutationRates()
+
+func SetMutationRates(rates MutationRates) {
+	Rates = rates
+}
+
 type NeuronGene struct {
-	neuronId        int
+	neuronId int
+	// Historical marking of the neuron, the same in every genome where it
+	// came from the same split. Inputs and outputs are marked by their
+	// position.
+	innovation      int
 	bias            float64
 	activation      Activation
 	hasBeencomputed bool
@@ -51,7 +95,7 @@ func CreateGenome(genomeId, numInputs, numOutputs int) *Genome {
 
 func (g *Genome) InitializeFromInitialConfig() {
 	for i := 0; i < g.numInputs+g.numOutputs; i++ {
-		newNeuron := NeuronGene{neuronId: i, activation: relu}
+		newNeuron := NeuronGene{neuronId: i, innovation: i, activation: relu}
 		g.neurons = append(g.neurons, &newNeuron)
 	}
 
@@ -59,7 +103,7 @@ func (g *Genome) InitializeFromInitialConfig() {
 		for out := g.numInputs; out < g.numInputs+g.numOutputs; out++ {
 			newLink := LinkGene{
 				linkId:    LinkId{inputId: in, outputId: out},
-				weight:    max(-10, min(rand.NormFloat64()*2, 10)),
+				weight:    max(-10, min(Rng.NormFloat64()*2, 10)),
 				isEnabled: true,
 			}
 			g.links = append(g.links, &newLink)
@@ -149,39 +193,39 @@ func (g *Genome) Mutate() {
 
 func (g *Genome) MutateHighVariability() {
 	for _, link := range g.links {
-		if rand.Float64() < 0.30 {
-			link.weight += max(-10, min(rand.NormFloat64()*0.25, 10))
+		if Rng.Float64() < 0.30 {
+			link.weight += max(-10, min(Rng.NormFloat64()*0.25, 10))
 		}
-		if rand.Float64() < 0.01 {
+		if Rng.Float64() < 0.01 {
 			link.isEnabled = !link.isEnabled
 		}
 	}
 
-	if rand.Float64() < 0.3 {
+	if Rng.Float64() < 0.3 {
 	    g.mutateAddLink()
 	}
-	if rand.Float64() < 0.2 {
+	if Rng.Float64() < 0.2 {
 		g.mutateRemoveLink()
 	}
-	if rand.Float64() < 0.1 {
+	if Rng.Float64() < 0.1 {
 		g.mutateAddNeuron()
 	}
-	if rand.Float64() < 0.02 {
+	if Rng.Float64() < 0.02 {
 		g.mutateRemoveNeuron()
 	}
 }
 
 func (g *Genome) mutateStructure() {
-	if rand.Float64() < 0.2 {
+	if Rng.Float64() < Rates.AddLink {
 	    g.mutateAddLink()
 	}
-	if rand.Float64() < 0.1 {
+	if Rng.Float64() < Rates.RemoveLink {
 		g.mutateRemoveLink()
 	}
-	if rand.Float64() < 0.05 {
+	if Rng.Float64() < Rates.AddNeuron {
 		g.mutateAddNeuron()
 	}
-	if rand.Float64() < 0.01 {
+	if Rng.Float64() < Rates.RemoveNeuron {
 		g.mutateRemoveNeuron()
 	}
 }
@@ -192,7 +236,7 @@ func (g *Genome) mutateRemoveNeuron() {
 		return
 	}
 
-	hiddenId := rand.Intn(n) + g.numInputs + g.numOutputs
+	hiddenId := Rng.IntN(n) + g.numInputs + g.numOutputs
 	for _, link := range g.links {
 		if link.linkId.inputId == hiddenId || link.linkId.outputId == hiddenId {
 			link.isEnabled = false
@@ -207,14 +251,22 @@ func (g *Genome) mutateAddNeuron() {
 		return
 	}
 
-	k := rand.Intn(len(g.links))
-	newId := len(g.neurons)
-	newNeuron := NeuronGene{neuronId: newId, activation: relu}
-
+	g.splitLink(g.links[Rng.IntN(len(g.links))])
+}
 
-	oldLink := g.links[k]
+// Replaces the link by a new neuron and links to and from it.
+func (g *Genome) splitLink(oldLink *LinkGene) {
 	oldLink.isEnabled = false
 
+	split := g.linkInnovation(oldLink)
+	innovation := splitInnovation(split, 0)
+	// A link split a second time gives a neuron of its own.
+	for n := 1; g.hasInnovation(innovation); n++ {
+		innovation = splitInnovation(split, n)
+	}
+	newId := len(g.neurons)
+	newNeuron := NeuronGene{neuronId: newId, innovation: innovation, activation: relu}
+
 	g.links = append(g.links, &LinkGene{
 		linkId: LinkId{inputId: oldLink.linkId.inputId, outputId: newId},
 		weight: 1,
@@ -230,19 +282,42 @@ func (g *Genome) mutateAddNeuron() {
 	g.numActiveNeurons++
 }
 
+// Marking of the neuron added by splitting a link for the nth time.
+// Rather than drawn from a counter, it is derived from the markings of the
+// neurons the link connected, so that the same split is marked the same in
+// every genome, in every run. It has bit 52 set, keeping it apart from the
+// markings of inputs and outputs while still fitting in a float64.
+func splitInnovation(split LinkId, n int) int {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, [3]int64{int64(split.inputId), int64(split.outputId), int64(n)})
+	return int(h.Sum64()&(1<<52-1) | 1<<52)
+}
+
+func (g *Genome) hasInnovation(innovation int) bool {
+	return slices.ContainsFunc(g.neurons, func(n *NeuronGene) bool { return n.innovation == innovation })
+}
+
+// Links are marked by the markings of the neurons they connect.
+func (g *Genome) linkInnovation(link *LinkGene) LinkId {
+	return LinkId{
+		inputId:  g.neurons[link.linkId.inputId].innovation,
+		outputId: g.neurons[link.linkId.outputId].innovation,
+	}
+}
+
 func (g *Genome) mutateRemoveLink() {
 	if len(g.links) == 0 {
 		return
 	}
 
-	k := rand.Intn(len(g.links))
+	k := Rng.IntN(len(g.links))
 	g.links[k] = g.links[len(g.links)-1]
 	g.links = g.links[:len(g.links)-1]
 }
 
 func (g *Genome) mutateAddLink() {
-	inputId := rand.Intn(g.numInputs)
-	outputId := rand.Intn(len(g.neurons) - g.numInputs) + g.numInputs
+	inputId := Rng.IntN(g.numInputs)
+	outputId := Rng.IntN(len(g.neurons) - g.numInputs) + g.numInputs
 	for _, link := range g.links {
 		linkId := link.linkId
 		if linkId.inputId == inputId && linkId.outputId == outputId {
@@ -253,7 +328,7 @@ func (g *Genome) mutateAddLink() {
 
 	g.links = append(g.links, &LinkGene{
 		linkId: LinkId{inputId: inputId, outputId: outputId},
-		weight: max(-10, min(rand.NormFloat64()*2, 10)),
+		weight: max(-10, min(Rng.NormFloat64()*2, 10)),
 		isEnabled: true,
 	})
 
@@ -290,10 +365,10 @@ func (g *Genome) hasCycle(cur int, visited, recStack []bool) bool {
 
 func (g *Genome) mutateValues() {
 	for _, link := range g.links {
-		if rand.Float64() < 0.10 {
-			link.weight += max(-10, min(rand.NormFloat64()*0.25, 10))
+		if Rng.Float64() < Rates.Weight {
+			link.weight += max(-10, min(Rng.NormFloat64()*Rates.WeightPower, 10))
 		}
-		if rand.Float64() < 0.01 {
+		if Rng.Float64() < Rates.ToggleLink {
 			link.isEnabled = !link.isEnabled
 		}
 	}
@@ -303,6 +378,76 @@ func (g Genome) Copypapy() *Genome {
 	return &g
 }
 
+func (g *Genome) Copy() *Genome {
+	c := *g
+	c.neurons = make([]*NeuronGene, len(g.neurons))
+	for i, neuron := range g.neurons {
+		n := *neuron
+		c.neurons[i] = &n
+	}
+	c.links = make([]*LinkGene, len(g.links))
+	for i, link := range g.links {
+		l := *link
+		c.links[i] = &l
+	}
+	return &c
+}
+
 func (g Genome) GetNumberOfNeurons() int {
 	return g.numActiveNeurons
 }
+
+func (g *Genome) GetNumberOfInputs() int {
+	return g.numInputs
+}
+
+func (g *Genome) GetNumberOfOutputs() int {
+	return g.numOutputs
+}
+
+func (g *Genome) GetNumberOfLinks() int {
+	n := 0
+	for _, link := range g.links {
+		if link.isEnabled {
+			n++
+		}
+	}
+	return n
+}
+
+// Compatibility distance from the original NEAT paper, links being matched
+// by their markings. Genomes which share most of their links with similar
+// weights are close to each other.
+func CompatibilityDistance(a, b *Genome) float64 {
+	weights := make(map[LinkId]float64)
+	for _, link := range a.links {
+		if link.isEnabled {
+			weights[a.linkInnovation(link)] = link.weight
+		}
+	}
+
+	matching := 0
+	weightDiff := 0.0
+	disjoint := 0
+	for _, link := range b.links {
+		if !link.isEnabled {
+			continue
+		}
+		innovation := b.linkInnovation(link)
+		if w, ok := weights[innovation]; ok {
+			matching++
+			weightDiff += math.Abs(w - link.weight)
+			delete(weights, innovation)
+		} else {
+			disjoint++
+		}
+	}
+	disjoint += len(weights)
+
+	n := max(1, matching+disjoint)
+	res := float64(disjoint) / float64(n)
+	if matching > 0 {
+		res += 0.4 * weightDiff / float64(matching)
+	}
+	return res
+}
@@ -0,0 +1,118 @@
+package neat
+
+import (
+	"math"
+	"slices"
+)
+
+// Scores a genome like an EvaluateFunc, also describing what it did so that
+// novel behaviours can be rewarded. Behaviours must all have the same length.
+type BehaviorFunc func(g *Genome) (fitness float64, behavior []float64)
+
+type NoveltyConfig struct {
+	// Nearest behaviours, in the generation and the archive, a behaviour
+	// is compared with.
+	K int
+	// Behaviours at least this novel are archived, the oldest ones being
+	// dropped once the archive is full.
+	ArchiveThreshold float64
+	ArchiveSize      int
+	// Share of novelty in the score genomes are selected by, 0 selecting by
+	// fitness alone and 1 by novelty alone.
+	Blend float64
+}
+
+func DefaultNoveltyConfig() NoveltyConfig {
+	return NoveltyConfig{K: 15, ArchiveThreshold: 0.3, ArchiveSize: 500, Blend: 0.5}
+}
+
+type NoveltyArchive struct {
+	Config    NoveltyConfig
+	Behaviors [][]float64
+}
+
+func NewNoveltyArchive(config NoveltyConfig) *NoveltyArchive {
+	return &NoveltyArchive{Config: config}
+}
+
+// Root mean square difference, so that behaviours of any length compare on
+// the same scale.
+func behaviorDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(max(1, len(a))))
+}
+
+// Mean distance from the behaviour to its K nearest neighbours among the
+// others and the archive.
+func (n *NoveltyArchive) Novelty(behavior []float64, others [][]float64) float64 {
+	var distances []float64
+	for _, other := range others {
+		distances = append(distances, behaviorDistance(behavior, other))
+	}
+	for _, archived := range n.Behaviors {
+		distances = append(distances, behaviorDistance(behavior, archived))
+	}
+	if len(distances) == 0 {
+		return 0
+	}
+
+	slices.Sort(distances)
+	k := min(max(1, n.Config.K), len(distances))
+	sum := 0.0
+	for _, d := range distances[:k] {
+		sum += d
+	}
+	return sum / float64(k)
+}
+
+// Novelty of every behaviour of a generation, archiving the novel ones
+// afterwards.
+func (n *NoveltyArchive) Evaluate(behaviors [][]float64) []float64 {
+	novelty := make([]float64, len(behaviors))
+	for i, b := range behaviors {
+		others := slices.Delete(slices.Clone(behaviors), i, i+1)
+		novelty[i] = n.Novelty(b, others)
+	}
+
+	for i, b := range behaviors {
+		if novelty[i] >= n.Config.ArchiveThreshold {
+			n.Behaviors = append(n.Behaviors, b)
+		}
+	}
+	if excess := len(n.Behaviors) - n.Config.ArchiveSize; excess > 0 {
+		n.Behaviors = slices.Delete(n.Behaviors, 0, excess)
+	}
+	return novelty
+}
+
+// Fitness and novelty scaled to [0, 1] within the generation and mixed
+// according to Blend.
+func (n *NoveltyArchive) Blend(fitness, novelty []float64) []float64 {
+	f := normalize(fitness)
+	nv := normalize(novelty)
+	scores := make([]float64, len(fitness))
+	for i := range scores {
+		scores[i] = (1-n.Config.Blend)*f[i] + n.Config.Blend*nv[i]
+	}
+	return scores
+}
+
+func normalize(values []float64) []float64 {
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lowest = min(lowest, v)
+		highest = max(highest, v)
+	}
+
+	normalized := make([]float64, len(values))
+	if highest > lowest {
+		for i, v := range values {
+			normalized[i] = (v - lowest) / (highest - lowest)
+		}
+	}
+	return normalized
+}
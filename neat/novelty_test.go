@@ -0,0 +1,85 @@
+package neat
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestNovelty(t *testing.T) {
+	tests := []struct {
+		name     string
+		k        int
+		archive  [][]float64
+		behavior []float64
+		others   [][]float64
+		want     float64
+	}{
+		{"alone", 3, nil, []float64{1}, nil, 0},
+		{"nearest neighbour", 1, nil, []float64{0}, [][]float64{{3}, {1}}, 1},
+		{"mean of the k nearest", 2, nil, []float64{0}, [][]float64{{3}, {1}, {7}}, 2},
+		{"fewer neighbours than k", 5, nil, []float64{0}, [][]float64{{3}, {1}}, 2},
+		{"archive counts as neighbours", 1, [][]float64{{0.5}}, []float64{0}, [][]float64{{3}}, 0.5},
+		{"root mean square distance", 1, nil, []float64{0, 0}, [][]float64{{3, 4}}, math.Sqrt(12.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := NewNoveltyArchive(NoveltyConfig{K: tt.k})
+			archive.Behaviors = tt.archive
+			if got := archive.Novelty(tt.behavior, tt.others); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Novelty() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateArchivesNovelBehaviors(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		archive   [][]float64
+		behaviors [][]float64
+		novelty   []float64
+		want      [][]float64
+	}{
+		{"only novel ones", 10, nil, [][]float64{{0}, {0}, {10}}, []float64{0, 0, 10}, [][]float64{{10}}},
+		{"oldest dropped when full", 2, [][]float64{{-20}, {-10}}, [][]float64{{0}, {0}, {10}}, []float64{0, 0, 10}, [][]float64{{-10}, {10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := NewNoveltyArchive(NoveltyConfig{K: 1, ArchiveThreshold: 1, ArchiveSize: tt.size})
+			archive.Behaviors = tt.archive
+
+			novelty := archive.Evaluate(tt.behaviors)
+			if !slices.Equal(novelty, tt.novelty) {
+				t.Errorf("novelty %v, want %v", novelty, tt.novelty)
+			}
+			if !slices.EqualFunc(archive.Behaviors, tt.want, slices.Equal) {
+				t.Errorf("archive %v, want %v", archive.Behaviors, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlend(t *testing.T) {
+	tests := []struct {
+		name    string
+		blend   float64
+		fitness []float64
+		novelty []float64
+		want    []float64
+	}{
+		{"fitness alone", 0, []float64{0, 5, 10}, []float64{1, 0, 0}, []float64{0, 0.5, 1}},
+		{"novelty alone", 1, []float64{0, 5, 10}, []float64{2, 0, 1}, []float64{1, 0, 0.5}},
+		{"half and half", 0.5, []float64{0, 10}, []float64{1, 0}, []float64{0.5, 0.5}},
+		{"constant scores count as 0", 0.5, []float64{3, 3}, []float64{0, 4}, []float64{0, 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := NewNoveltyArchive(NoveltyConfig{Blend: tt.blend})
+			if got := archive.Blend(tt.fitness, tt.novelty); !slices.Equal(got, tt.want) {
+				t.Errorf("Blend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package neat
+
+import (
+	"math"
+	"slices"
+)
+
+// Scores a genome, higher being better. Fitness may be negative.
+type EvaluateFunc func(g *Genome) float64
+
+type PopulationConfig struct {
+	Size       int
+	NumInputs  int
+	NumOutputs int
+	// Best genomes of every species copied unchanged to the next generation.
+	Elitism int
+	// Fraction of every species, the fittest first, allowed to have offspring.
+	SurvivalThreshold float64
+	// Chance of an offspring having two parents instead of being a mutated
+	// copy of one.
+	CrossoverRate          float64
+	CompatibilityThreshold float64
+	// Generations a species may go without improving before it is dropped.
+	// The best species are never dropped.
+	StagnationLimit  int
+	ProtectedSpecies int
+}
+
+func DefaultPopulationConfig(numInputs, numOutputs int) PopulationConfig {
+	return PopulationConfig{
+		Size:                   100,
+		NumInputs:              numInputs,
+		NumOutputs:             numOutputs,
+		Elitism:                1,
+		SurvivalThreshold:      0.2,
+		CrossoverRate:          0.75,
+		CompatibilityThreshold: 1.0,
+		StagnationLimit:        15,
+		ProtectedSpecies:       2,
+	}
+}
+
+type Species struct {
+	Id             int
+	Members        []*Genome
+	representative *Genome
+	bestFitness    float64
+	lastImproved   int
+}
+
+// A generational NEAT trainer. Every generation is evaluated, split into
+// species, and replaced by the offspring of the fittest members of every
+// species, species sharing the offspring according to their average fitness.
+type Population struct {
+	Config     PopulationConfig
+	Genomes    []*Genome
+	Species    []*Species
+	Generation int
+	// Best genome ever evaluated.
+	Best        *Genome
+	BestFitness float64
+
+	fitness       map[*Genome]float64
+	nextGenomeId  int
+	nextSpeciesId int
+}
+
+type GenerationStats struct {
+	Generation  int
+	BestFitness float64
+	MeanFitness float64
+	Species     int
+	Neurons     float64
+	Links       float64
+	// Only set when novelty is blended in.
+	MeanNovelty float64
+	Archive     int
+}
+
+func NewPopulation(config PopulationConfig) *Population {
+	p := &Population{Config: config, BestFitness: math.Inf(-1), nextSpeciesId: 1}
+	for range config.Size {
+		g := CreateGenome(p.newGenomeId(), config.NumInputs, config.NumOutputs)
+		g.InitializeFromInitialConfig()
+		p.Genomes = append(p.Genomes, g)
+	}
+	return p
+}
+
+// Starts from mutated copies of an existing genome, e.g. a brain evolved in
+// the world.
+func NewPopulationFrom(config PopulationConfig, ancestor *Genome) *Population {
+	p := &Population{Config: config, BestFitness: math.Inf(-1), nextSpeciesId: 1}
+	for range config.Size {
+		g := ancestor.Copy()
+		g.genomeId = p.newGenomeId()
+		g.Mutate()
+		p.Genomes = append(p.Genomes, g)
+	}
+	return p
+}
+
+func (p *Population) newGenomeId() int {
+	p.nextGenomeId++
+	return p.nextGenomeId
+}
+
+// Score the genome was selected by in the last generation, its fitness
+// unless novelty was blended in.
+func (p *Population) Fitness(g *Genome) float64 {
+	return p.fitness[g]
+}
+
+// Evaluates the current generation and breeds the next one.
+func (p *Population) Step(evaluate EvaluateFunc) GenerationStats {
+	fitness := make([]float64, len(p.Genomes))
+	for i, g := range p.Genomes {
+		fitness[i] = evaluate(g)
+	}
+	return p.advance(fitness, fitness)
+}
+
+// Like Step, but genomes are selected by their fitness blended with the
+// novelty of their behaviour.
+func (p *Population) StepNovelty(evaluate BehaviorFunc, archive *NoveltyArchive) GenerationStats {
+	fitness := make([]float64, len(p.Genomes))
+	behaviors := make([][]float64, len(p.Genomes))
+	for i, g := range p.Genomes {
+		fitness[i], behaviors[i] = evaluate(g)
+	}
+	novelty := archive.Evaluate(behaviors)
+
+	stats := p.advance(fitness, archive.Blend(fitness, novelty))
+	for _, n := range novelty {
+		stats.MeanNovelty += n
+	}
+	stats.MeanNovelty /= float64(max(1, len(novelty)))
+	stats.Archive = len(archive.Behaviors)
+	return stats
+}
+
+// Breeds the next generation from the scores of the current one. The best
+// genome is still the one with the highest fitness.
+func (p *Population) advance(fitness, scores []float64) GenerationStats {
+	p.fitness = make(map[*Genome]float64, len(p.Genomes))
+	stats := GenerationStats{Generation: p.Generation, BestFitness: math.Inf(-1)}
+	for i, g := range p.Genomes {
+		f := fitness[i]
+		p.fitness[g] = scores[i]
+		stats.MeanFitness += f
+		stats.Neurons += float64(g.GetNumberOfNeurons())
+		stats.Links += float64(g.GetNumberOfLinks())
+		if f > stats.BestFitness {
+			stats.BestFitness = f
+		}
+		if f > p.BestFitness {
+			p.BestFitness = f
+			p.Best = g.Copy()
+		}
+	}
+	n := float64(max(1, len(p.Genomes)))
+	stats.MeanFitness /= n
+	stats.Neurons /= n
+	stats.Links /= n
+
+	p.speciate()
+	p.removeStagnantSpecies()
+	stats.Species = len(p.Species)
+	p.reproduce()
+	p.Generation++
+	return stats
+}
+
+// Runs the given number of generations, report being called after every one
+// of them. Returns the best genome found.
+func (p *Population) Run(evaluate EvaluateFunc, generations int, report func(GenerationStats)) *Genome {
+	for range generations {
+		stats := p.Step(evaluate)
+		if report != nil {
+			report(stats)
+		}
+	}
+	return p.Best
+}
+
+func (p *Population) speciate() {
+	for _, s := range p.Species {
+		s.Members = nil
+	}
+
+	for _, g := range p.Genomes {
+		var species *Species
+		for _, s := range p.Species {
+			if CompatibilityDistance(s.representative, g) <= p.Config.CompatibilityThreshold {
+				species = s
+				break
+			}
+		}
+		if species == nil {
+			species = &Species{
+				Id:             p.nextSpeciesId,
+				representative: g,
+				bestFitness:    math.Inf(-1),
+				lastImproved:   p.Generation,
+			}
+			p.nextSpeciesId++
+			p.Species = append(p.Species, species)
+		}
+		species.Members = append(species.Members, g)
+	}
+
+	p.Species = slices.DeleteFunc(p.Species, func(s *Species) bool { return len(s.Members) == 0 })
+	for _, s := range p.Species {
+		slices.SortStableFunc(s.Members, func(a, b *Genome) int {
+			return cmpFitness(p.fitness[b], p.fitness[a])
+		})
+		s.representative = s.Members[Rng.IntN(len(s.Members))]
+		if best := p.fitness[s.Members[0]]; best > s.bestFitness {
+			s.bestFitness = best
+			s.lastImproved = p.Generation
+		}
+	}
+}
+
+func cmpFitness(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (p *Population) removeStagnantSpecies() {
+	byBest := slices.Clone(p.Species)
+	slices.SortStableFunc(byBest, func(a, b *Species) int { return cmpFitness(b.bestFitness, a.bestFitness) })
+	protected := byBest[:min(len(byBest), p.Config.ProtectedSpecies)]
+
+	kept := slices.DeleteFunc(slices.Clone(p.Species), func(s *Species) bool {
+		return p.Generation-s.lastImproved > p.Config.StagnationLimit && !slices.Contains(protected, s)
+	})
+	if len(kept) > 0 {
+		p.Species = kept
+	}
+}
+
+// Splits the next generation among the species in proportion to their
+// average fitness, shifted so that the least fit genome counts as 0.
+func (p *Population) offspringCounts() []int {
+	lowest := math.Inf(1)
+	for _, s := range p.Species {
+		for _, g := range s.Members {
+			lowest = min(lowest, p.fitness[g])
+		}
+	}
+
+	shares := make([]float64, len(p.Species))
+	total := 0.0
+	for i, s := range p.Species {
+		for _, g := range s.Members {
+			shares[i] += p.fitness[g] - lowest
+		}
+		shares[i] /= float64(len(s.Members))
+		total += shares[i]
+	}
+
+	counts := make([]int, len(p.Species))
+	remainders := make([]float64, len(p.Species))
+	assigned := 0
+	for i := range p.Species {
+		exact := float64(p.Config.Size) / float64(len(p.Species))
+		if total > 0 {
+			exact = float64(p.Config.Size) * shares[i] / total
+		}
+		counts[i] = int(exact)
+		remainders[i] = exact - float64(counts[i])
+		assigned += counts[i]
+	}
+
+	// Whatever rounding left out goes to the largest remainders.
+	order := make([]int, len(p.Species))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmpFitness(remainders[b], remainders[a]) })
+	for i := 0; assigned < p.Config.Size; i++ {
+		counts[order[i%len(order)]]++
+		assigned++
+	}
+	return counts
+}
+
+func (p *Population) reproduce() {
+	var next []*Genome
+	for i, count := range p.offspringCounts() {
+		s := p.Species[i]
+
+		for _, elite := range s.Members[:min(p.Config.Elitism, count, len(s.Members))] {
+			next = append(next, elite.Copy())
+			count--
+		}
+
+		parents := s.Members[:max(1, int(math.Ceil(p.Config.SurvivalThreshold*float64(len(s.Members)))))]
+		for range count {
+			mother := parents[Rng.IntN(len(parents))]
+			var child *Genome
+			if len(parents) > 1 && Rng.Float64() < p.Config.CrossoverRate {
+				father := parents[Rng.IntN(len(parents))]
+				if p.fitness[father] > p.fitness[mother] {
+					mother, father = father, mother
+				}
+				child = Crossover(mother, father)
+			} else {
+				child = mother.Copy()
+			}
+			child.Mutate()
+			next = append(next, child)
+		}
+	}
+
+	for _, g := range next {
+		g.genomeId = p.newGenomeId()
+	}
+	p.Genomes = next
+}
+
+// Child of two genomes, fitter being the fitter parent. Links are matched by
+// their markings, not by where their neurons happen to be in either
+// genome: the child has the structure of the fitter parent, matching links
+// taking their weight from either parent at random. A link disabled in
+// either parent is likely to stay disabled.
+func Crossover(fitter, other *Genome) *Genome {
+	child := fitter.Copy()
+
+	links := make(map[LinkId]*LinkGene, len(other.links))
+	for _, link := range other.links {
+		links[other.linkInnovation(link)] = link
+	}
+	for _, link := range child.links {
+		match, ok := links[child.linkInnovation(link)]
+		if !ok {
+			continue
+		}
+		if Rng.Float64() < 0.5 {
+			link.weight = match.weight
+		}
+		if !link.isEnabled || !match.isEnabled {
+			link.isEnabled = Rng.Float64() >= 0.75
+		}
+	}
+	return child
+}
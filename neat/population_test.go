@@ -0,0 +1,168 @@
+package neat
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func seededGenome(t *testing.T, numInputs, numOutputs int) *Genome {
+	t.Helper()
+	SetRng(rand.New(rand.NewPCG(1, 2)))
+	g := CreateGenome(1, numInputs, numOutputs)
+	g.InitializeFromInitialConfig()
+	return g
+}
+
+func TestOffspringCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		species [][]float64
+		want    []int
+	}{
+		{"equal fitness splits evenly", 10, [][]float64{{1, 1}, {1}}, []int{5, 5}},
+		{"in proportion to the shifted mean", 8, [][]float64{{0}, {3}, {1}}, []int{0, 6, 2}},
+		{"mean over the members", 6, [][]float64{{0, 4}, {2}}, []int{3, 3}},
+		{"rounding goes to the first of equal remainders", 10, [][]float64{{0, 3}, {0, 3}, {0, 3}}, []int{4, 3, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Population{Config: PopulationConfig{Size: tt.size}, fitness: make(map[*Genome]float64)}
+			for _, fitness := range tt.species {
+				s := &Species{}
+				for _, f := range fitness {
+					g := CreateGenome(0, 1, 1)
+					p.fitness[g] = f
+					s.Members = append(s.Members, g)
+				}
+				p.Species = append(p.Species, s)
+			}
+
+			got := p.offspringCounts()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("offspringCounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveStagnantSpecies(t *testing.T) {
+	type species struct {
+		bestFitness  float64
+		lastImproved int
+	}
+	tests := []struct {
+		name      string
+		protected int
+		species   []species
+		want      []int
+	}{
+		{"improving species are kept", 0, []species{{1, 10}, {2, 8}}, []int{1, 2}},
+		{"stagnant species are dropped", 0, []species{{1, 10}, {2, 2}}, []int{1}},
+		{"the best species are protected", 1, []species{{1, 10}, {2, 2}}, []int{1, 2}},
+		{"protection goes by best fitness", 1, []species{{3, 2}, {2, 2}, {1, 10}}, []int{1, 3}},
+		{"the last species are never dropped", 0, []species{{1, 2}, {2, 2}}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Population{
+				Config:     PopulationConfig{StagnationLimit: 5, ProtectedSpecies: tt.protected},
+				Generation: 10,
+			}
+			for i, s := range tt.species {
+				p.Species = append(p.Species, &Species{Id: i + 1, bestFitness: s.bestFitness, lastImproved: s.lastImproved})
+			}
+
+			p.removeStagnantSpecies()
+			var got []int
+			for _, s := range p.Species {
+				got = append(got, s.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept species %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpeciate(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		offsets   []float64
+		want      int
+	}{
+		{"copies share a species", 1, []float64{0, 0, 0}, 1},
+		{"close weights share a species", 1, []float64{0, 0.5, 1}, 1},
+		{"distant weights split", 1, []float64{0, 0, 10}, 2},
+		{"a loose threshold merges them", 10, []float64{0, 0, 10}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := seededGenome(t, 3, 2)
+			p := &Population{
+				Config:        PopulationConfig{CompatibilityThreshold: tt.threshold},
+				fitness:       make(map[*Genome]float64),
+				nextSpeciesId: 1,
+			}
+			for _, offset := range tt.offsets {
+				g := base.Copy()
+				for _, link := range g.links {
+					link.weight += offset
+				}
+				p.Genomes = append(p.Genomes, g)
+			}
+
+			p.speciate()
+			if len(p.Species) != tt.want {
+				t.Errorf("got %d species, want %d", len(p.Species), tt.want)
+			}
+			members := 0
+			for _, s := range p.Species {
+				members += len(s.Members)
+			}
+			if members != len(p.Genomes) {
+				t.Errorf("species have %d members, want %d", members, len(p.Genomes))
+			}
+		})
+	}
+}
+
+func TestLinksMatchByInnovation(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     int
+		matching bool
+	}{
+		{"same split", 0, 0, true},
+		{"different splits", 0, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := seededGenome(t, 2, 1)
+			a, b := base.Copy(), base.Copy()
+			// Both new neurons end up at the same position, whichever link
+			// was split.
+			a.splitLink(a.links[tt.a])
+			b.splitLink(b.links[tt.b])
+
+			distance := CompatibilityDistance(a, b)
+			if tt.matching && distance != 0 {
+				t.Errorf("distance %g between genomes with the same split, want 0", distance)
+			}
+			if !tt.matching && distance == 0 {
+				t.Error("links of unrelated neurons at the same position matched")
+			}
+
+			for range 20 {
+				child := Crossover(a, b)
+				for i, link := range child.links {
+					if !tt.matching && link.weight != a.links[i].weight {
+						t.Fatalf("link %d took the weight of an unrelated link", i)
+					}
+				}
+			}
+		})
+	}
+}
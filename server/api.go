@@ -0,0 +1,332 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/artificial-life/game"
+)
+
+const MAX_STEP = 100_000
+
+// Lets HTTP clients drive the simulation. The world is only ever touched
+// from the goroutine running the simulation: requests queue commands which
+// it runs between ticks by calling RunPending.
+type Controller struct {
+	tick        func()
+	snapshotDir string
+	commands    chan func()
+
+	mu             sync.Mutex
+	paused         bool
+	ticksPerSecond float64
+}
+
+func NewController(tick func(), snapshotDir string, ticksPerSecond float64) *Controller {
+	return &Controller{
+		tick:           tick,
+		snapshotDir:    snapshotDir,
+		commands:       make(chan func(), 16),
+		ticksPerSecond: ticksPerSecond,
+	}
+}
+
+func (c *Controller) RunPending() {
+	for {
+		select {
+		case command := <-c.commands:
+			command()
+		default:
+			return
+		}
+	}
+}
+
+func (c *Controller) IsPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+func (c *Controller) SetPaused(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = paused
+}
+
+// Wall time between two ticks, 0 meaning as fast as possible.
+func (c *Controller) TickDuration() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ticksPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / c.ticksPerSecond)
+}
+
+func (c *Controller) TicksPerSecond() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ticksPerSecond
+}
+
+func (c *Controller) SetTicksPerSecond(ticksPerSecond float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ticksPerSecond = ticksPerSecond
+}
+
+// Runs f on the simulation goroutine and waits for it to be done.
+func (c *Controller) do(r *http.Request, f func() (any, error)) (any, error) {
+	type result struct {
+		value any
+		err   error
+	}
+	done := make(chan result, 1)
+
+	select {
+	case c.commands <- func() {
+		value, err := f()
+		done <- result{value, err}
+	}:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...any) error {
+	return httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func notFound(format string, args ...any) error {
+	return httpError{status: http.StatusNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// Browsers tell where the page making a request comes from, and a page from
+// another site must not drive the simulation. Other clients send no origin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func writeJSON(w http.ResponseWriter, value any, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		status := http.StatusInternalServerError
+		var httpErr httpError
+		if errors.As(err, &httpErr) {
+			status = httpErr.status
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(value)
+}
+
+func decode(r *http.Request, v any) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+type statusResponse struct {
+	Ticks          int     `json:"ticks"`
+	Paused         bool    `json:"paused"`
+	TicksPerSecond float64 `json:"ticksPerSecond"`
+	Animals        int     `json:"animals"`
+	Plants         int     `json:"plants"`
+	Corpses        int     `json:"corpses"`
+}
+
+func (c *Controller) status(r *http.Request) (any, error) {
+	return c.do(r, func() (any, error) {
+		return statusResponse{
+			Ticks:          game.Ticks,
+			Paused:         c.IsPaused(),
+			TicksPerSecond: c.TicksPerSecond(),
+			Animals:        len(game.Animals),
+			Plants:         len(game.FoodBlocks),
+			Corpses:        len(game.Corpses),
+		}, nil
+	})
+}
+
+// Queues a change to the world, applied at the start of the next tick so
+// that it is recorded and replayed like anything else happening in it.
+func (c *Controller) intervene(w http.ResponseWriter, r *http.Request, i game.Intervention) {
+	value, err := c.do(r, func() (any, error) {
+		if err := i.Check(); err != nil {
+			return nil, badRequest("%v", err)
+		}
+		game.Intervene(i)
+		return map[string]any{"intervention": i.Kind, "x": i.X, "y": i.Y, "tick": game.Ticks}, nil
+	})
+	if err != nil {
+		writeJSON(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(value)
+}
+
+func (c *Controller) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		value, err := c.status(r)
+		writeJSON(w, value, err)
+	})
+
+	mux.HandleFunc("POST /api/pause", func(w http.ResponseWriter, r *http.Request) {
+		c.SetPaused(true)
+		value, err := c.status(r)
+		writeJSON(w, value, err)
+	})
+
+	mux.HandleFunc("POST /api/resume", func(w http.ResponseWriter, r *http.Request) {
+		c.SetPaused(false)
+		value, err := c.status(r)
+		writeJSON(w, value, err)
+	})
+
+	mux.HandleFunc("PUT /api/tick-rate", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TicksPerSecond float64 `json:"ticksPerSecond"`
+		}
+		if err := decode(r, &body); err != nil {
+			writeJSON(w, nil, err)
+			return
+		}
+		if body.TicksPerSecond < 0 {
+			writeJSON(w, nil, badRequest("ticksPerSecond can't be negative"))
+			return
+		}
+		c.SetTicksPerSecond(body.TicksPerSecond)
+		value, err := c.status(r)
+		writeJSON(w, value, err)
+	})
+
+	mux.HandleFunc("POST /api/step", func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Ticks int `json:"ticks"`
+		}{Ticks: 1}
+		if err := decode(r, &body); err != nil {
+			writeJSON(w, nil, err)
+			return
+		}
+		if body.Ticks < 1 || body.Ticks > MAX_STEP {
+			writeJSON(w, nil, badRequest("ticks must be between 1 and %d", MAX_STEP))
+			return
+		}
+		_, err := c.do(r, func() (any, error) {
+			for i := 0; i < body.Ticks; i++ {
+				c.tick()
+			}
+			return nil, nil
+		})
+		if err != nil {
+			writeJSON(w, nil, err)
+			return
+		}
+		value, err := c.status(r)
+		writeJSON(w, value, err)
+	})
+
+	mux.HandleFunc("POST /api/animals", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			X    float64 `json:"x"`
+			Y    float64 `json:"y"`
+			Type string  `json:"type"`
+		}
+		if err := decode(r, &body); err != nil {
+			writeJSON(w, nil, err)
+			return
+		}
+		animalType, err := game.ParseAnimalType(body.Type)
+		if err != nil {
+			writeJSON(w, nil, badRequest("%v", err))
+			return
+		}
+		c.intervene(w, r, game.Intervention{Kind: game.SPAWN_ANIMAL, X: body.X, Y: body.Y, AnimalType: animalType})
+	})
+
+	mux.HandleFunc("GET /api/animals/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, nil, badRequest("invalid animal id %q", r.PathValue("id")))
+			return
+		}
+		value, err := c.do(r, func() (any, error) {
+			a := game.FindAnimal(id)
+			if a == nil {
+				return nil, notFound("no living animal with id %d", id)
+			}
+			return a.Info(true), nil
+		})
+		writeJSON(w, value, err)
+	})
+
+	mux.HandleFunc("POST /api/food", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			X float64 `json:"x"`
+			Y float64 `json:"y"`
+		}
+		if err := decode(r, &body); err != nil {
+			writeJSON(w, nil, err)
+			return
+		}
+		c.intervene(w, r, game.Intervention{Kind: game.SPAWN_PLANT, X: body.X, Y: body.Y})
+	})
+
+	mux.HandleFunc("POST /api/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		value, err := c.do(r, func() (any, error) {
+			path, err := game.WriteCheckpoint(c.snapshotDir)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"path": path, "ticks": game.Ticks}, nil
+		})
+		writeJSON(w, value, err)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && !sameOrigin(r) {
+			writeJSON(w, nil, httpError{status: http.StatusForbidden, err: fmt.Errorf("cross-origin requests from %s are not allowed", r.Header.Get("Origin"))})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
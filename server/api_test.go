@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns a controller whose commands are run the way the game loop would,
+// and how many times it ticked.
+func testController(t *testing.T) (*Controller, *int) {
+	t.Helper()
+	ticks := new(int)
+	c := NewController(func() { *ticks++ }, t.TempDir(), 30)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				c.RunPending()
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+	return c, ticks
+}
+
+func serve(h http.Handler, method, target, body, origin string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlers(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		// Checked against the decoded response.
+		check func(t *testing.T, c *Controller, ticks int, response map[string]any)
+	}{
+		{"status", "GET", "/api/status", "", http.StatusOK, func(t *testing.T, c *Controller, ticks int, response map[string]any) {
+			if response["ticksPerSecond"] != 30.0 || response["paused"] != false {
+				t.Errorf("status = %v", response)
+			}
+		}},
+		{"pause", "POST", "/api/pause", "", http.StatusOK, func(t *testing.T, c *Controller, ticks int, response map[string]any) {
+			if !c.IsPaused() || response["paused"] != true {
+				t.Errorf("paused = %v, response = %v", c.IsPaused(), response)
+			}
+		}},
+		{"resume", "POST", "/api/resume", "", http.StatusOK, func(t *testing.T, c *Controller, ticks int, response map[string]any) {
+			if c.IsPaused() {
+				t.Error("still paused")
+			}
+		}},
+		{"tick rate", "PUT", "/api/tick-rate", `{"ticksPerSecond": 120}`, http.StatusOK, func(t *testing.T, c *Controller, ticks int, response map[string]any) {
+			if c.TicksPerSecond() != 120 || response["ticksPerSecond"] != 120.0 {
+				t.Errorf("ticks per second = %g, response = %v", c.TicksPerSecond(), response)
+			}
+		}},
+		{"negative tick rate", "PUT", "/api/tick-rate", `{"ticksPerSecond": -1}`, http.StatusBadRequest, nil},
+		{"invalid body", "PUT", "/api/tick-rate", `{"ticksPerSecond": `, http.StatusBadRequest, nil},
+		{"step once by default", "POST", "/api/step", "", http.StatusOK, func(t *testing.T, c *Controller, ticks int, response map[string]any) {
+			if ticks != 1 {
+				t.Errorf("ticked %d times, want 1", ticks)
+			}
+		}},
+		{"step", "POST", "/api/step", `{"ticks": 25}`, http.StatusOK, func(t *testing.T, c *Controller, ticks int, response map[string]any) {
+			if ticks != 25 {
+				t.Errorf("ticked %d times, want 25", ticks)
+			}
+		}},
+		{"step nothing", "POST", "/api/step", `{"ticks": 0}`, http.StatusBadRequest, nil},
+		{"step too far", "POST", "/api/step", `{"ticks": 100001}`, http.StatusBadRequest, nil},
+		{"unknown animal type", "POST", "/api/animals", `{"x": 1, "y": 1, "type": "dragon"}`, http.StatusBadRequest, nil},
+		{"invalid animal id", "GET", "/api/animals/x", "", http.StatusBadRequest, nil},
+		{"no such animal", "GET", "/api/animals/12345", "", http.StatusNotFound, nil},
+		{"unknown route", "GET", "/api/nothing", "", http.StatusNotFound, nil},
+		{"wrong method", "GET", "/api/pause", "", http.StatusMethodNotAllowed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ticks := testController(t)
+			w := serve(c.Handler(), tt.method, tt.target, tt.body, "")
+			if w.Code != tt.status {
+				t.Fatalf("%s %s = %d %s, want %d", tt.method, tt.target, w.Code, w.Body, tt.status)
+			}
+			if tt.check == nil {
+				return
+			}
+			var response map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, c, *ticks, response)
+		})
+	}
+}
+
+func TestCrossOriginRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		origin string
+		status int
+	}{
+		{"no origin", "POST", "", http.StatusOK},
+		{"same origin", "POST", "http://example.com", http.StatusOK},
+		{"same origin, different case", "POST", "http://EXAMPLE.com", http.StatusOK},
+		{"other site", "POST", "http://evil.example", http.StatusForbidden},
+		{"other port", "POST", "http://example.com:8080", http.StatusForbidden},
+		{"invalid origin", "POST", "://", http.StatusForbidden},
+		{"reading is allowed", "GET", "http://evil.example", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testController(t)
+			target := "/api/pause"
+			if tt.method == "GET" {
+				target = "/api/status"
+			}
+			// httptest requests are for example.com.
+			w := serve(c.Handler(), tt.method, target, "", tt.origin)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s from %q = %d %s, want %d", tt.method, target, tt.origin, w.Code, w.Body, tt.status)
+			}
+			if tt.status == http.StatusForbidden && c.IsPaused() {
+				t.Error("a rejected request paused the simulation")
+			}
+		})
+	}
+}
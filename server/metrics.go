@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"example.com/artificial-life/game"
+)
+
+var tickBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+type metricsState struct {
+	sample        game.StatsSample
+	ticks         int
+	tickRate      float64
+	foodCoverage  float64
+	publishedTime time.Time
+}
+
+// Metrics of a running simulation in the Prometheus text exposition format.
+// The game loop only ever swaps pointers and bumps atomic counters, so
+// scraping never slows it down.
+type Metrics struct {
+	state        atomic.Pointer[metricsState]
+	bucketCounts []atomic.Uint64
+	tickCount    atomic.Uint64
+	tickSumBits  atomic.Uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{bucketCounts: make([]atomic.Uint64, len(tickBuckets))}
+}
+
+func (m *Metrics) ObserveTick(d time.Duration) {
+	seconds := d.Seconds()
+	for i, bound := range tickBuckets {
+		if seconds <= bound {
+			m.bucketCounts[i].Add(1)
+		}
+	}
+	m.tickCount.Add(1)
+	for {
+		old := m.tickSumBits.Load()
+		sum := math.Float64frombits(old) + seconds
+		if m.tickSumBits.CompareAndSwap(old, math.Float64bits(sum)) {
+			break
+		}
+	}
+}
+
+// Publishes the state of the world, it has to be called from the game loop
+// between ticks.
+func (m *Metrics) Publish() {
+	now := time.Now()
+	state := &metricsState{sample: game.SampleStats(), ticks: game.Ticks, publishedTime: now}
+
+	cells := (game.Game.WorldSize / 16) * (game.Game.WorldSize / 16)
+	if cells > 0 {
+		state.foodCoverage = float64(state.sample.Plants) / cells
+	}
+	if last := m.state.Load(); last != nil {
+		if elapsed := now.Sub(last.publishedTime).Seconds(); elapsed > 0 {
+			state.tickRate = float64(state.ticks-last.ticks) / elapsed
+		}
+	}
+
+	m.state.Store(state)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	if state := m.state.Load(); state != nil {
+		s := state.sample
+
+		metric("alife_ticks_total", "counter", "Ticks simulated since the world was created.")
+		fmt.Fprintf(w, "alife_ticks_total %d\n", state.ticks)
+		metric("alife_tick_rate", "gauge", "Ticks simulated per second of wall time.")
+		fmt.Fprintf(w, "alife_tick_rate %g\n", state.tickRate)
+		metric("alife_population", "gauge", "Living animals by type.")
+		fmt.Fprintf(w, "alife_population{type=\"prey\"} %d\n", s.Prey)
+		fmt.Fprintf(w, "alife_population{type=\"hunter\"} %d\n", s.Hunters)
+		metric("alife_carnivores", "gauge", "Living animals which digest meat better than plants.")
+		fmt.Fprintf(w, "alife_carnivores %d\n", s.Carnivores)
+		metric("alife_species", "gauge", "Species with at least one living animal.")
+		fmt.Fprintf(w, "alife_species %d\n", s.Species)
+		metric("alife_brain_neurons_mean", "gauge", "Average number of active neurons per brain.")
+		fmt.Fprintf(w, "alife_brain_neurons_mean %g\n", s.Neurons.Mean)
+		metric("alife_brain_links_mean", "gauge", "Average number of enabled links per brain.")
+		fmt.Fprintf(w, "alife_brain_links_mean %g\n", s.Links.Mean)
+		metric("alife_food_coverage", "gauge", "Fraction of the world's cells holding an edible plant.")
+		fmt.Fprintf(w, "alife_food_coverage %g\n", state.foodCoverage)
+		metric("alife_plant_fp", "gauge", "Food points left in all plants.")
+		fmt.Fprintf(w, "alife_plant_fp %g\n", s.PlantFp)
+		metric("alife_corpses", "gauge", "Corpses which haven't decayed yet.")
+		fmt.Fprintf(w, "alife_corpses %d\n", s.Corpses)
+	}
+
+	metric("alife_tick_duration_seconds", "histogram", "Time spent simulating a tick.")
+	for i, bound := range tickBuckets {
+		fmt.Fprintf(w, "alife_tick_duration_seconds_bucket{le=\"%g\"} %d\n", bound, m.bucketCounts[i].Load())
+	}
+	count := m.tickCount.Load()
+	fmt.Fprintf(w, "alife_tick_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(w, "alife_tick_duration_seconds_sum %g\n", math.Float64frombits(m.tickSumBits.Load()))
+	fmt.Fprintf(w, "alife_tick_duration_seconds_count %d\n", count)
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+
+	"example.com/artificial-life/game"
+)
+
+type streamAnimal struct {
+	x, y    float64
+	heading float64
+	kind    game.AnimalType
+}
+
+type streamFood struct {
+	x, y int
+}
+
+type streamFrame struct {
+	tick      int
+	worldSize float64
+	animals   map[int]streamAnimal
+	food      map[streamFood]int
+}
+
+type Viewport struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+func (v Viewport) contains(x, y float64) bool {
+	return x >= v.X && x <= v.X+v.W && y >= v.Y && y <= v.Y+v.H
+}
+
+// Only what changed since the previous message is sent. Animals are
+// [id, x, y, heading, type] and plants [x, y, size in percent].
+type streamDelta struct {
+	Tick      int          `json:"tick"`
+	WorldSize float64      `json:"worldSize,omitempty"`
+	Animals   [][5]float64 `json:"animals,omitempty"`
+	Gone      []int        `json:"gone,omitempty"`
+	Food      [][3]int     `json:"food,omitempty"`
+	Eaten     [][2]int     `json:"eaten,omitempty"`
+}
+
+type streamClient struct {
+	conn   *wsConn
+	notify chan struct{}
+
+	mu       sync.Mutex
+	latest   *streamFrame
+	viewport *Viewport
+
+	sentAnimals map[int]streamAnimal
+	sentFood    map[streamFood]int
+	sentTick    int
+}
+
+// Streams the state of the world over websockets. Clients may send
+// {"viewport": {"x", "y", "w", "h"}} to only hear about what is inside it.
+type Stream struct {
+	mu        sync.Mutex
+	clients   map[*streamClient]bool
+	joined    bool
+	lastTicks int
+}
+
+func NewStream() *Stream {
+	return &Stream{clients: make(map[*streamClient]bool), lastTicks: -1}
+}
+
+func round(v float64, decimals float64) float64 {
+	return math.Round(v*decimals) / decimals
+}
+
+// Publishes the state of the world, it has to be called from the game loop
+// between ticks. Nothing is done unless the world moved on or somebody
+// joined since the last call.
+func (s *Stream) Publish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) == 0 || (game.Ticks == s.lastTicks && !s.joined) {
+		return
+	}
+	s.lastTicks = game.Ticks
+	s.joined = false
+
+	frame := &streamFrame{
+		tick:      game.Ticks,
+		worldSize: game.Game.WorldSize,
+		animals:   make(map[int]streamAnimal, len(game.Animals)),
+		food:      make(map[streamFood]int, len(game.FoodBlocks)),
+	}
+	for _, a := range game.Animals {
+		x, y := a.GetPos()
+		frame.animals[a.GetId()] = streamAnimal{
+			x:       round(x, 10),
+			y:       round(y, 10),
+			heading: round(a.GetTheta(), 100),
+			kind:    a.GetType(),
+		}
+	}
+	for _, food := range game.FoodBlocks {
+		size := int(math.Round(food.GetSize() * 100))
+		if size <= 0 {
+			continue
+		}
+		x, y := food.GetPos()
+		frame.food[streamFood{int(x), int(y)}] = size
+	}
+
+	// Slow clients skip frames instead of holding back the simulation.
+	for c := range s.clients {
+		c.mu.Lock()
+		c.latest = frame
+		c.mu.Unlock()
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebsocket(w, r)
+	if err != nil {
+		return
+	}
+
+	c := &streamClient{
+		conn:        conn,
+		notify:      make(chan struct{}, 1),
+		sentAnimals: make(map[int]streamAnimal),
+		sentFood:    make(map[streamFood]int),
+		sentTick:    -1,
+	}
+	s.mu.Lock()
+	s.clients[c] = true
+	s.joined = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go c.readViewports(done)
+	c.writeFrames(done)
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	conn.Close()
+}
+
+func (c *streamClient) readViewports(done chan struct{}) {
+	defer close(done)
+	for {
+		message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var body struct {
+			Viewport *Viewport `json:"viewport"`
+		}
+		if json.Unmarshal(message, &body) != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		c.viewport = body.Viewport
+		c.mu.Unlock()
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *streamClient) writeFrames(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.notify:
+		}
+
+		c.mu.Lock()
+		frame, viewport := c.latest, c.viewport
+		c.mu.Unlock()
+		if frame == nil {
+			continue
+		}
+
+		message, err := json.Marshal(c.delta(frame, viewport))
+		if err != nil {
+			return
+		}
+		if err := c.conn.WriteText(message); err != nil {
+			return
+		}
+	}
+}
+
+// Diffs the frame against what the client was already sent.
+func (c *streamClient) delta(frame *streamFrame, viewport *Viewport) streamDelta {
+	d := streamDelta{Tick: frame.tick}
+	if c.sentTick < 0 {
+		d.WorldSize = frame.worldSize
+	}
+	c.sentTick = frame.tick
+
+	visible := func(x, y float64) bool {
+		return viewport == nil || viewport.contains(x, y)
+	}
+
+	for id, a := range frame.animals {
+		if !visible(a.x, a.y) {
+			continue
+		}
+		if sent, ok := c.sentAnimals[id]; ok && sent == a {
+			continue
+		}
+		c.sentAnimals[id] = a
+		d.Animals = append(d.Animals, [5]float64{float64(id), a.x, a.y, a.heading, float64(a.kind)})
+	}
+	for id := range c.sentAnimals {
+		if a, ok := frame.animals[id]; !ok || !visible(a.x, a.y) {
+			delete(c.sentAnimals, id)
+			d.Gone = append(d.Gone, id)
+		}
+	}
+
+	for key, size := range frame.food {
+		if !visible(float64(key.x), float64(key.y)) {
+			continue
+		}
+		if c.sentFood[key] == size {
+			continue
+		}
+		c.sentFood[key] = size
+		d.Food = append(d.Food, [3]int{key.x, key.y, size})
+	}
+	for key := range c.sentFood {
+		if _, ok := frame.food[key]; !ok || !visible(float64(key.x), float64(key.y)) {
+			delete(c.sentFood, key)
+			d.Eaten = append(d.Eaten, [2]int{key.x, key.y})
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"example.com/artificial-life/game"
+)
+
+func TestDelta(t *testing.T) {
+	var (
+		a  = streamAnimal{x: 10, y: 10, heading: 1, kind: game.PREY}
+		b  = streamAnimal{x: 90, y: 90, heading: 2, kind: game.HUNTER}
+		a2 = streamAnimal{x: 11, y: 10, heading: 1, kind: game.PREY}
+	)
+	frame := func(tick int, animals map[int]streamAnimal, food map[streamFood]int) *streamFrame {
+		return &streamFrame{tick: tick, worldSize: 100, animals: animals, food: food}
+	}
+	corner := &Viewport{X: 0, Y: 0, W: 50, H: 50}
+
+	// Each step is diffed against what the steps before it sent.
+	steps := []struct {
+		name     string
+		frame    *streamFrame
+		viewport *Viewport
+		want     streamDelta
+	}{
+		{"everything at first",
+			frame(1, map[int]streamAnimal{1: a, 2: b}, map[streamFood]int{{20, 20}: 50}), nil,
+			streamDelta{Tick: 1, WorldSize: 100,
+				Animals: [][5]float64{{1, 10, 10, 1, float64(game.PREY)}, {2, 90, 90, 2, float64(game.HUNTER)}},
+				Food:    [][3]int{{20, 20, 50}}}},
+		{"nothing changed",
+			frame(2, map[int]streamAnimal{1: a, 2: b}, map[streamFood]int{{20, 20}: 50}), nil,
+			streamDelta{Tick: 2}},
+		{"moved and grew",
+			frame(3, map[int]streamAnimal{1: a2, 2: b}, map[streamFood]int{{20, 20}: 60}), nil,
+			streamDelta{Tick: 3,
+				Animals: [][5]float64{{1, 11, 10, 1, float64(game.PREY)}},
+				Food:    [][3]int{{20, 20, 60}}}},
+		{"died and eaten",
+			frame(4, map[int]streamAnimal{1: a2}, map[streamFood]int{}), nil,
+			streamDelta{Tick: 4, Gone: []int{2}, Eaten: [][2]int{{20, 20}}}},
+		{"back",
+			frame(5, map[int]streamAnimal{1: a2, 2: b}, map[streamFood]int{{20, 20}: 60, {80, 80}: 10}), nil,
+			streamDelta{Tick: 5,
+				Animals: [][5]float64{{2, 90, 90, 2, float64(game.HUNTER)}},
+				Food:    [][3]int{{20, 20, 60}, {80, 80, 10}}}},
+		{"out of the viewport",
+			frame(6, map[int]streamAnimal{1: a2, 2: b}, map[streamFood]int{{20, 20}: 60, {80, 80}: 10}), corner,
+			streamDelta{Tick: 6, Gone: []int{2}, Eaten: [][2]int{{80, 80}}}},
+		{"outside the viewport changes aren't sent",
+			frame(7, map[int]streamAnimal{1: a2, 2: a}, map[streamFood]int{{20, 20}: 60, {80, 80}: 90}), corner,
+			streamDelta{Tick: 7, Animals: [][5]float64{{2, 10, 10, 1, float64(game.PREY)}}}},
+	}
+
+	c := &streamClient{
+		sentAnimals: make(map[int]streamAnimal),
+		sentFood:    make(map[streamFood]int),
+		sentTick:    -1,
+	}
+	for _, step := range steps {
+		got := c.delta(step.frame, step.viewport)
+		// Maps are diffed in no particular order.
+		slices.SortFunc(got.Animals, func(x, y [5]float64) int { return int(x[0] - y[0]) })
+		slices.Sort(got.Gone)
+		slices.SortFunc(got.Food, func(x, y [3]int) int { return x[0] - y[0] })
+		slices.SortFunc(got.Eaten, func(x, y [2]int) int { return x[0] - y[0] })
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: delta = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// A canvas viewer for browsers, it expects the Stream to be served at
+// /stream on the same address.
+//
+//go:embed viewer.html
+var viewerPage []byte
+
+func ServeViewer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(viewerPage)
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A connection reading the given bytes and writing into out.
+func testConn(t *testing.T, in []byte, out *bytes.Buffer) *wsConn {
+	t.Helper()
+	conn, other := net.Pipe()
+	t.Cleanup(func() {
+		conn.Close()
+		other.Close()
+	})
+	return &wsConn{conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(in)), bufio.NewWriter(out))}
+}
+
+// Frames the payload the way clients have to, masked.
+func clientFrame(fin bool, opcode byte, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestWriteFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		header []byte
+	}{
+		{"short", 5, []byte{0x81, 5}},
+		{"16 bit length", 200, []byte{0x81, 126, 0, 200}},
+		{"64 bit length", 70000, []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0x11, 0x70}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			payload := bytes.Repeat([]byte("a"), tt.length)
+			if err := testConn(t, nil, &out).WriteText(payload); err != nil {
+				t.Fatal(err)
+			}
+			want := append(tt.header, payload...)
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Errorf("frame starts with %v, want %v", out.Bytes()[:len(tt.header)], tt.header)
+			}
+		})
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	unmasked := []byte{0x81, 2, 'h', 'i'}
+	tests := []struct {
+		name    string
+		in      [][]byte
+		message string
+		// Any error will do when nil and there is no message.
+		err error
+		// What the server answers with, if anything.
+		out []byte
+	}{
+		{"text", [][]byte{clientFrame(true, opText, []byte("hello"))}, "hello", nil, nil},
+		{"16 bit length", [][]byte{clientFrame(true, opText, bytes.Repeat([]byte("a"), 300))}, string(bytes.Repeat([]byte("a"), 300)), nil, nil},
+		{"fragmented", [][]byte{
+			clientFrame(false, opText, []byte("hel")),
+			clientFrame(true, opContinuation, []byte("lo")),
+		}, "hello", nil, nil},
+		{"ping is answered", [][]byte{
+			clientFrame(true, opPing, []byte("p")),
+			clientFrame(true, opText, []byte("hello")),
+		}, "hello", nil, []byte{0x8A, 1, 'p'}},
+		{"pong is ignored", [][]byte{
+			clientFrame(true, opPong, nil),
+			clientFrame(true, opText, []byte("hello")),
+		}, "hello", nil, nil},
+		{"close", [][]byte{clientFrame(true, opClose, []byte{0x03, 0xE8, 'b', 'y', 'e'})}, "", io.EOF, []byte{0x88, 2, 0x03, 0xE8}},
+		{"unmasked", [][]byte{unmasked}, "", nil, nil},
+		{"too big", [][]byte{clientFrame(true, opText, make([]byte, MAX_CLIENT_MESSAGE+1))}, "", errMessageTooBig, nil},
+		{"too big once put together", [][]byte{
+			clientFrame(false, opText, make([]byte, MAX_CLIENT_MESSAGE)),
+			clientFrame(true, opContinuation, []byte("a")),
+		}, "", errMessageTooBig, nil},
+		{"unknown opcode", [][]byte{clientFrame(true, 0x3, nil)}, "", nil, nil},
+		{"truncated", [][]byte{clientFrame(true, opText, []byte("hello"))[:4]}, "", io.ErrUnexpectedEOF, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			message, err := testConn(t, bytes.Join(tt.in, nil), &out).ReadMessage()
+			wantErr := tt.err != nil || tt.message == ""
+			switch {
+			case wantErr && err == nil:
+				t.Fatalf("ReadMessage() = %q, want an error", message)
+			case tt.err != nil && !errors.Is(err, tt.err):
+				t.Fatalf("ReadMessage() error = %v, want %v", err, tt.err)
+			case !wantErr && err != nil:
+				t.Fatal(err)
+			}
+			if string(message) != tt.message {
+				t.Errorf("ReadMessage() = %q, want %q", message, tt.message)
+			}
+			if !bytes.Equal(out.Bytes(), tt.out) {
+				t.Errorf("answered %v, want %v", out.Bytes(), tt.out)
+			}
+		})
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	valid := func() http.Header {
+		return http.Header{
+			"Connection":            {"keep-alive, Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			// The key and accept from RFC 6455.
+			"Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="},
+		}
+	}
+	tests := []struct {
+		name   string
+		method string
+		change func(h http.Header, host string)
+		status int
+	}{
+		{"valid", "GET", func(h http.Header, host string) {}, http.StatusSwitchingProtocols},
+		{"same origin", "GET", func(h http.Header, host string) { h.Set("Origin", "http://"+host) }, http.StatusSwitchingProtocols},
+		{"not an upgrade", "GET", func(h http.Header, host string) { h.Del("Upgrade") }, http.StatusBadRequest},
+		{"no connection upgrade", "GET", func(h http.Header, host string) { h.Set("Connection", "keep-alive") }, http.StatusBadRequest},
+		{"old version", "GET", func(h http.Header, host string) { h.Set("Sec-Websocket-Version", "8") }, http.StatusBadRequest},
+		{"no key", "GET", func(h http.Header, host string) { h.Del("Sec-Websocket-Key") }, http.StatusBadRequest},
+		{"wrong method", "POST", func(h http.Header, host string) {}, http.StatusBadRequest},
+		{"other site", "GET", func(h http.Header, host string) { h.Set("Origin", "http://evil.example") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := websocketServer(t)
+			h := valid()
+			tt.change(h, server.Listener.Addr().String())
+			r, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header = h
+			resp, err := http.DefaultTransport.RoundTrip(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("handshake = %s, want %d", resp.Status, tt.status)
+			}
+			if tt.status != http.StatusSwitchingProtocols {
+				return
+			}
+			if accept := resp.Header.Get("Sec-Websocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+				t.Errorf("Sec-WebSocket-Accept = %q", accept)
+			}
+		})
+	}
+}
+
+// Upgrades connections and reads from them until they are closed.
+func websocketServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebsocket(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStream(t *testing.T) {
+	stream := NewStream()
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: "+server.Listener.Addr().String()+"\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake = %s", resp.Status)
+	}
+
+	// The client may not have joined yet, until it has nothing is sent.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+				stream.Publish()
+			}
+		}
+	}()
+
+	var header [2]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x81 || header[1]&0x80 != 0 || header[1]&0x7F >= 126 {
+		t.Fatalf("frame header = %v, want an unmasked short text frame", header)
+	}
+	payload := make([]byte, header[1])
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	var d streamDelta
+	if err := json.Unmarshal(payload, &d); err != nil {
+		t.Fatalf("%v: %s", err, payload)
+	}
+
+	// Closing is echoed.
+	conn.Write(clientFrame(true, opClose, []byte{0x03, 0xE8}))
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x88 {
+		t.Errorf("answered a close with opcode %#x", header[0]&0x0F)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+
+	"example.com/artificial-life/game"
+)
+
+const RUN_SUMMARY_FILE = "summary.json"
+
+// Outcome of a single run, written to its output directory when it ends.
+type runSummary struct {
+	Seed           uint64  `json:"seed"`
+	Extinct        bool    `json:"extinct"`
+	SurvivalTicks  int     `json:"survivalTicks"`
+	PeakPopulation int     `json:"peakPopulation"`
+	Population     int     `json:"population"`
+	Species        int     `json:"species"`
+	MeanFitness    float64 `json:"meanFitness"`
+}
+
+func writeRunSummary(path string) error {
+	sample := game.SampleStats()
+	summary := runSummary{
+		Seed:           seed,
+		Extinct:        game.IsExtinct(),
+		SurvivalTicks:  game.Ticks - startTick,
+		PeakPopulation: peakPopulation,
+		Population:     sample.Population,
+		Species:        sample.Species,
+		MeanFitness:    sample.Fitness.Mean,
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// A grid of parameters, every combination of their values being run
+// Replicates times with seeds Seed, Seed+1 and so on. Config holds the
+// parameters shared by every run.
+type sweepDefinition struct {
+	Config     json.RawMessage              `json:"config"`
+	Parameters map[string][]json.RawMessage `json:"parameters"`
+	Replicates int                          `json:"replicates"`
+	Ticks      int                          `json:"ticks"`
+	Seed       uint64                       `json:"seed"`
+}
+
+type sweepPoint struct {
+	assignments []string
+	config      game.GameConfig
+}
+
+func readSweep(path string) (sweepDefinition, error) {
+	def := sweepDefinition{Replicates: 1, Ticks: 60 * 60 * 10, Seed: 1}
+	file, err := os.Open(path)
+	if err != nil {
+		return def, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&def); err != nil {
+		return def, fmt.Errorf("%s: %w", path, err)
+	}
+	if def.Replicates < 1 {
+		return def, fmt.Errorf("%s: replicates must be at least 1", path)
+	}
+	if def.Ticks < 1 {
+		return def, fmt.Errorf("%s: ticks must be at least 1", path)
+	}
+	return def, nil
+}
+
+// Below their minimum, populations are replaced and immigrants keep coming
+// in, so no world would ever go extinct. Sweeps turn both off unless their
+// config or parameters turn them back on.
+func sweepBaseConfig() game.GameConfig {
+	c := game.DefaultConfig()
+	c.Population.Prey.Min = 0
+	c.Population.Prey.ImmigrationPeriod = 0
+	c.Population.Hunters.Min = 0
+	c.Population.Hunters.ImmigrationPeriod = 0
+	return c
+}
+
+// Whether the runs of a configuration can go extinct at all, their
+// survival meaning nothing otherwise.
+func canGoExtinct(c game.GameConfig) bool {
+	for _, p := range []game.PopulationControl{c.Population.Prey, c.Population.Hunters} {
+		if p.Min > 0 || p.ImmigrationPeriod > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Every combination of the parameters, in a fixed order.
+func (def sweepDefinition) points() ([]sweepPoint, error) {
+	base := sweepBaseConfig()
+	if def.Config != nil {
+		var err error
+		base, err = game.ReadConfigOver(base, bytes.NewReader(def.Config))
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	points := []sweepPoint{{config: base}}
+	for _, key := range slices.Sorted(maps.Keys(def.Parameters)) {
+		values := def.Parameters[key]
+		if len(values) == 0 {
+			return nil, fmt.Errorf("parameter %s has no values", key)
+		}
+
+		var next []sweepPoint
+		for _, p := range points {
+			for _, value := range values {
+				assignment := key + "=" + string(value)
+				config := p.config
+				if err := config.Set(assignment); err != nil {
+					return nil, err
+				}
+				next = append(next, sweepPoint{
+					assignments: append(slices.Clone(p.assignments), assignment),
+					config:      config,
+				})
+			}
+		}
+		points = next
+	}
+
+	for _, p := range points {
+		if err := p.config.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(p.assignments, " "), err)
+		}
+	}
+	return points, nil
+}
+
+type sweepRun struct {
+	point     int
+	replicate int
+	seed      uint64
+	dir       string
+	summary   runSummary
+	err       error
+	// Never started, the sweep having been interrupted first.
+	skipped bool
+}
+
+// The world lives in globals, so runs can't share a process. Each one is a
+// headless child process writing to its own directory.
+func (r *sweepRun) execute(ctx context.Context, exe string, config game.GameConfig, ticks int, keepEvents bool) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+	configPath := filepath.Join(r.dir, "sweep-config.json")
+	file, err := os.Create(configPath)
+	if err != nil {
+		return err
+	}
+	err = config.Write(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	log, err := os.Create(filepath.Join(r.dir, "log.txt"))
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	args := []string{"headless",
+		"-config", configPath,
+		"-seed", strconv.FormatUint(r.seed, 10),
+		"-ticks", strconv.Itoa(ticks),
+		"-out", r.dir,
+		"-fast",
+		"-autosave-minutes", "0",
+		"-stats-interval", "600",
+	}
+	if !keepEvents {
+		args = append(args, "-events=")
+	}
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run %s: %w, see %s", r.dir, err, log.Name())
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dir, RUN_SUMMARY_FILE))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.summary)
+}
+
+func runSweep(name string, args []string) error {
+	fs := newFlagSet(name, "<sweep-file>")
+	fs.StringVar(&outDir, "out", "sweep", "directory where the runs and the summary table are written")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "runs simulated at the same time")
+	keepEvents := fs.Bool("events", false, "keep the event log of every run, which can grow large")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	def, err := readSweep(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	points, err := def.points()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var runs []*sweepRun
+	for i := range points {
+		for r := 0; r < def.Replicates; r++ {
+			runs = append(runs, &sweepRun{
+				point:     i,
+				replicate: r,
+				seed:      def.Seed + uint64(r),
+				dir:       filepath.Join(outDir, fmt.Sprintf("run-%03d-%02d", i, r)),
+			})
+		}
+	}
+	fmt.Printf("Sweeping %d configurations %d times each, %d runs on %d jobs\n", len(points), def.Replicates, len(runs), *jobs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pending := make(chan *sweepRun)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for range max(1, *jobs) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range pending {
+				r.err = r.execute(ctx, exe, points[r.point].config, def.Ticks, *keepEvents)
+
+				mu.Lock()
+				done++
+				if r.err != nil {
+					fmt.Printf("[%d/%d] %s failed: %v\n", done, len(runs), r.dir, r.err)
+				} else {
+					fmt.Printf("[%d/%d] %s: survived %d ticks, peak population %d\n",
+						done, len(runs), r.dir, r.summary.SurvivalTicks, r.summary.PeakPopulation)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	dispatched := 0
+dispatch:
+	for _, r := range runs {
+		select {
+		case pending <- r:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	var errs []error
+	for _, r := range runs[dispatched:] {
+		r.skipped = true
+	}
+	if skipped := len(runs) - dispatched; skipped > 0 {
+		errs = append(errs, fmt.Errorf("interrupted, %d of %d runs never started: %w", skipped, len(runs), ctx.Err()))
+	}
+	for _, r := range runs {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+	if err := writeSweepSummary(points, runs); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func meanAndDeviation(values []float64) (mean, sd float64) {
+	if len(values) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		sd += (v - mean) * (v - mean)
+	}
+	if len(values) > 1 {
+		sd = math.Sqrt(sd / float64(len(values)-1))
+	}
+	return mean, sd
+}
+
+// One row per configuration, aggregating its replicates, both printed and
+// written as CSV to the output directory.
+func writeSweepSummary(points []sweepPoint, runs []*sweepRun) error {
+	header := []string{
+		"configuration", "parameters", "runs", "extinctions",
+		"survival_ticks_mean", "survival_ticks_sd",
+		"peak_population_mean", "peak_population_sd",
+		"final_fitness_mean", "final_fitness_sd",
+	}
+	var rows [][]string
+	for i, p := range points {
+		var survival, peak, fitness []float64
+		extinctions := 0
+		for _, r := range runs {
+			if r.point != i || r.skipped || r.err != nil {
+				continue
+			}
+			survival = append(survival, float64(r.summary.SurvivalTicks))
+			peak = append(peak, float64(r.summary.PeakPopulation))
+			fitness = append(fitness, r.summary.MeanFitness)
+			if r.summary.Extinct {
+				extinctions++
+			}
+		}
+
+		ftoa := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+		survivalMean, survivalSd := meanAndDeviation(survival)
+		peakMean, peakSd := meanAndDeviation(peak)
+		fitnessMean, fitnessSd := meanAndDeviation(fitness)
+		row := []string{
+			strconv.Itoa(i), strings.Join(p.assignments, " "), strconv.Itoa(len(survival)), strconv.Itoa(extinctions),
+			ftoa(survivalMean), ftoa(survivalSd),
+			ftoa(peakMean), ftoa(peakSd),
+			ftoa(fitnessMean), ftoa(fitnessSd),
+		}
+		if !canGoExtinct(p.config) {
+			row[3], row[4], row[5] = "n/a", "n/a", "n/a"
+		}
+		rows = append(rows, row)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+
+	file, err := os.Create(filepath.Join(outDir, "summary.csv"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	w.Write(header)
+	w.WriteAll(rows)
+	return w.Error()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+
+	"example.com/artificial-life/game"
+	"example.com/artificial-life/neat"
+)
+
+var brainPath string
+
+// Brain the animals of a new world start from, if any.
+func readBrain() (*neat.Genome, error) {
+	if brainPath == "" {
+		return nil, nil
+	}
+	return readGenome(brainPath, 0)
+}
+
+func writeGenome(path string, g *neat.Genome) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func runTrain(name string, args []string) error {
+	fs := newFlagSet(name, "")
+	configFlags(fs)
+	generations := fs.Int("generations", 50, "generations to breed")
+	size := fs.Int("population", 100, "genomes in every generation")
+	arenaTicks := fs.Int("arena-ticks", 60*60, "ticks every genome is evaluated for")
+	arenaSize := fs.Float64("arena-size", 0, "size of the arena, 1024 unless the configuration sets it")
+	arenaAnimals := fs.Int("arena-animals", 4, "copies of the genome living in the arena at the same time")
+	replicates := fs.Int("replicates", 3, "arenas every genome is evaluated in, grown from seeds 1, 2 and so on")
+	out := fs.String("out", "brain.json", "file where the best genome is written whenever it improves")
+	novelty := neat.DefaultNoveltyConfig()
+	fs.Float64Var(&novelty.Blend, "novelty", 0, "share of novelty in the score genomes are selected by, from 0 (fitness alone) to 1 (novelty alone)")
+	fs.IntVar(&novelty.K, "novelty-k", novelty.K, "nearest behaviours the novelty of a behaviour is measured against")
+	fs.Float64Var(&novelty.ArchiveThreshold, "novelty-threshold", novelty.ArchiveThreshold, "novelty above which a behaviour is archived")
+	fs.IntVar(&novelty.ArchiveSize, "novelty-archive", novelty.ArchiveSize, "behaviours kept in the archive")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	arena := game.DefaultArena()
+	arena.Ticks = *arenaTicks
+	arena.Animals = *arenaAnimals
+	arena.Seeds = nil
+	for i := range *replicates {
+		arena.Seeds = append(arena.Seeds, uint64(i+1))
+	}
+	if configPath != "" {
+		config, err := game.LoadConfigFile(configPath)
+		if err != nil {
+			return err
+		}
+		config.InitialAnimalChance = 0
+		config.Population.Prey.Min = 0
+		config.Population.Hunters.Min = 0
+		arena.Config = config
+	}
+	for _, override := range configOverrides {
+		if err := arena.Config.Set(override); err != nil {
+			return err
+		}
+	}
+	if *arenaSize > 0 {
+		arena.Config.WorldSize = *arenaSize
+	}
+	if err := arena.Config.Validate(); err != nil {
+		return err
+	}
+	// A bad terrain file fails here rather than in the first evaluation.
+	if err := game.InitWorld(arena.Config); err != nil {
+		return err
+	}
+	if arena.Animals < 1 || len(arena.Seeds) < 1 {
+		return errors.New("train: -arena-animals and -replicates must be at least 1")
+	}
+	if novelty.Blend < 0 || novelty.Blend > 1 {
+		return fmt.Errorf("train: -novelty must be between 0 and 1, got %g", novelty.Blend)
+	}
+
+	ancestor, err := readBrain()
+	if err != nil {
+		return err
+	}
+
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	fmt.Println("Seed", seed)
+	game.Seed(seed)
+	neat.SetMutationRates(arena.Config.Mutation)
+
+	config := neat.DefaultPopulationConfig(2*arena.Config.Animals.FovRays+1, 3)
+	config.Size = *size
+	var population *neat.Population
+	if ancestor != nil {
+		population = neat.NewPopulationFrom(config, ancestor)
+	} else {
+		population = neat.NewPopulation(config)
+	}
+
+	var archive *neat.NoveltyArchive
+	if novelty.Blend > 0 {
+		archive = neat.NewNoveltyArchive(novelty)
+	}
+	best := population.BestFitness
+	for range *generations {
+		var stats neat.GenerationStats
+		if archive != nil {
+			stats = population.StepNovelty(arena.EvaluateBehavior, archive)
+		} else {
+			stats = population.Step(arena.Evaluate)
+		}
+
+		fmt.Printf("generation %d: best %.1f, mean %.1f, %d species, %.1f neurons, %.1f links",
+			stats.Generation, stats.BestFitness, stats.MeanFitness, stats.Species, stats.Neurons, stats.Links)
+		if archive != nil {
+			fmt.Printf(", novelty %.3f, %d archived", stats.MeanNovelty, stats.Archive)
+		}
+		fmt.Println()
+
+		if population.BestFitness > best {
+			best = population.BestFitness
+			if err := writeGenome(*out, population.Best); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("Best fitness %.1f, written to %s\n", best, *out)
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build cgo && !nogui
+
+package main
+
+import (
+	"image/color"
+
+	"example.com/artificial-life/game"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"golang.org/x/image/colornames"
+)
+
+var (
+	fovColor   = color.NRGBA{255, 255, 255, 60}
+	rayColor   = color.NRGBA{255, 255, 255, 90}
+	plantColor = colornames.Lime
+	meatColor  = colornames.Red
+	wallColor  = colornames.Gray
+)
+
+// Draws the animal's field of view as it was when it last looked around: the
+// cone its rays sweep and where each of them hit something.
+func drawVision(imd *imdraw.IMDraw, a *game.Animal) {
+	rays := a.Rays()
+	if len(rays) == 0 {
+		return
+	}
+
+	center := pixel.V(a.GetPos())
+	w, _ := a.GetDim()
+	radius := float64(a.GetViewingDistance()) * w / 2
+	from, to := rays[0].Theta, rays[len(rays)-1].Theta
+	imd.Color = fovColor
+	imd.Push(center)
+	imd.CircleArc(radius, from, to, 1)
+	for _, theta := range []float64{from, to} {
+		imd.Push(center, center.Add(pixel.V(radius, 0).Rotated(theta)))
+		imd.Line(1)
+	}
+
+	for _, r := range rays {
+		x, y := a.RayPoint(r, r.Length)
+		imd.Color = rayColor
+		imd.Push(center, pixel.V(x, y))
+		imd.Line(1)
+
+		if r.Blocked {
+			hit(imd, a, r, r.Length, wallColor)
+		}
+		if r.Plant > 0 {
+			hit(imd, a, r, r.Plant, plantColor)
+		}
+		if r.Meat > 0 {
+			hit(imd, a, r, r.Meat, meatColor)
+		}
+	}
+}
+
+func hit(imd *imdraw.IMDraw, a *game.Animal, r game.Ray, step int, c color.Color) {
+	x, y := a.RayPoint(r, step)
+	imd.Color = c
+	imd.Push(pixel.V(x, y))
+	imd.Circle(3, 0)
+}
@@ -0,0 +1,183 @@
+//go:build cgo && !nogui
+
+package main
+
+import (
+	"math"
+	"time"
+
+	"example.com/artificial-life/game"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"golang.org/x/image/colornames"
+)
+
+// The window needs cgo and the X11 or Wayland headers to build, without
+// them (CGO_ENABLED=0 or -tags nogui) the simulation only runs headless.
+func runWindow() error {
+	opengl.Run(run)
+	return nil
+}
+
+func run() {
+	cfg := opengl.WindowConfig{
+		Title:  "Artificial Life",
+		Bounds: pixel.R(0, 0, 1024, 768),
+		VSync:  true,
+	}
+	win, err := opengl.NewWindow(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer win.Destroy()
+
+	win.Clear(colornames.Skyblue)
+
+	var (
+		camPos       = pixel.ZV
+		camSpeed     = 500.0
+		baseCamSpeed = 500.0
+		camZoom      = 1.0
+		camZoomSpeed = 1.2
+		// trees        []*pixel.Sprite
+		// matrices     []pixel.Matrix
+	)
+
+	terrainPic := game.TerrainMap.Picture()
+	terrain := pixel.NewSprite(terrainPic, terrainPic.Bounds())
+
+	angle := 0.0
+	last := time.Now()
+	lastTick := time.Now()
+	camPos.X = game.Game.WorldSize / 2
+	camPos.Y = game.Game.WorldSize / 2
+	inspect := newInspector()
+	vision := imdraw.New(nil)
+	showVision := false
+
+	for !win.Closed() {
+		center := win.Bounds().Center()
+		if pos, ok := inspect.follow(); ok {
+			camPos = pos
+			// Keep the animal in the middle of what the panel leaves visible.
+			center.X -= INSPECTOR_WIDTH / 2
+		}
+		cam := pixel.IM.Scaled(camPos, camZoom).Moved(center.Sub(camPos))
+		win.SetMatrix(cam)
+		inspect.handleInput(win, cam)
+
+		dt := time.Since(last).Seconds()
+		last = time.Now()
+
+		controller.RunPending()
+		if win.JustPressed(pixel.KeySpace) {
+			controller.SetPaused(!controller.IsPaused())
+		}
+
+		tickDuration := controller.TickDuration()
+		shouldUpdate := false
+		if controller.IsPaused() {
+			lastTick = last
+		} else if last.Sub(lastTick) >= tickDuration {
+			lastTick = lastTick.Add(tickDuration)
+			if tickDuration == 0 || last.Sub(lastTick) > time.Second {
+				lastTick = last
+			}
+			shouldUpdate = true
+		}
+
+		camZoom *= math.Pow(camZoomSpeed, win.MouseScroll().Y)
+		camSpeed = baseCamSpeed / camZoom
+
+		win.Clear(colornames.Forestgreen)
+		terrain.Draw(win, pixel.IM.Scaled(pixel.ZV, game.TILE_SIZE).Moved(terrain.Frame().Center().Scaled(game.TILE_SIZE)))
+
+		if win.Pressed(pixel.KeyDown) {
+			camPos.Y -= camSpeed * dt
+		}
+		if win.Pressed(pixel.KeyUp) {
+			camPos.Y += camSpeed * dt
+		}
+		if win.Pressed(pixel.KeyLeft) {
+			camPos.X -= camSpeed * dt
+		}
+		if win.Pressed(pixel.KeyRight) {
+			camPos.X += camSpeed * dt
+		}
+
+		for _, food := range game.FoodBlocks {
+
+			x, y := food.GetPos()
+			mat := pixel.IM
+			mat = mat.Scaled(pixel.ZV, 0.3+0.7*food.GetSize())
+			mat = mat.Rotated(pixel.ZV, angle)
+			mat = mat.Moved(pixel.Vec{X: x, Y: y})
+
+			food.GetCurrSprite().Draw(win, mat)
+		}
+
+		for _, corpse := range game.Corpses {
+			x, y := corpse.GetPos()
+			freshness := corpse.GetFreshness()
+			mat := pixel.IM
+			mat = mat.Scaled(pixel.ZV, 0.5+freshness/2)
+			mat = mat.Moved(pixel.Vec{X: x, Y: y})
+
+			corpse.GetSprite().DrawColorMask(win, mat, pixel.RGB(0.4+freshness/2, 0.2, 0.2))
+		}
+
+		if replay != nil {
+			if win.JustPressed(pixel.KeyComma) {
+				scrub(-10 * game.Game.TicksPerSecond)
+			}
+			if win.JustPressed(pixel.KeyPeriod) {
+				scrub(10 * game.Game.TicksPerSecond)
+			}
+		}
+
+		if shouldUpdate {
+			tick()
+		}
+		publishStream()
+		if shouldStop() {
+			win.SetClosed(true)
+		}
+
+		for _, animal := range game.Animals {
+
+			tickProportion := 0.0
+			if tickDuration > 0 && !controller.IsPaused() {
+				tickProportion = float64(last.Sub(lastTick)) / float64(tickDuration)
+			}
+			dx, dy := animal.GetLastMove()
+			dx *= tickProportion
+			dy *= tickProportion
+
+			x, y := animal.GetPos()
+
+			mat := pixel.IM
+			mat = mat.Rotated(pixel.ZV, angle)
+			mat = mat.Moved(pixel.Vec{X: x + dx, Y: y + dy})
+
+			animal.GetSprite().Draw(win, mat)
+		}
+		if win.JustPressed(pixel.KeyV) {
+			showVision = !showVision
+		}
+		if showVision {
+			vision.Clear()
+			for _, animal := range game.Animals {
+				drawVision(vision, animal)
+			}
+			vision.Draw(win)
+		}
+		inspect.drawWorld(win)
+
+		win.SetMatrix(pixel.IM)
+		inspect.drawPanel(win)
+
+		win.Update()
+	}
+}
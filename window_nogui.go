@@ -0,0 +1,9 @@
+//go:build !cgo || nogui
+
+package main
+
+import "errors"
+
+func runWindow() error {
+	return errors.New("built without a window, run it headless")
+}